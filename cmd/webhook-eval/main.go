@@ -0,0 +1,31 @@
+// Command webhook-eval is a template for a binary which evaluates generic webhooks against manifests offline.
+// Copy it into your project and register your webhooks in main, then run e.g.
+//
+//	webhook-eval -baseline deployed/ manifests/
+//
+// to evaluate all manifests in the 'manifests' directory as CREATE, or as UPDATE if a manifest with the same kind,
+// namespace and name exists in the 'deployed' directory. The command exits non-zero if any request is denied.
+//
+//	webhook-eval -replay recordings/
+//
+// replays the requests recorded by a webhook.Recorder and exits non-zero if any verdict or patch changed.
+package main
+
+import (
+	"os"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhookeval"
+)
+
+func main() {
+	evaluator := webhookeval.New()
+
+	// TODO register your webhooks here, e.g.
+	//
+	//	if err := evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &pod.Webhook{}); err != nil {
+	//		fmt.Fprintln(os.Stderr, err)
+	//		os.Exit(webhookeval.ExitError)
+	//	}
+
+	os.Exit(evaluator.Main(os.Args[1:], os.Stdout))
+}
@@ -0,0 +1,108 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+// ConfigMapServer is a fake API server which serves the list and the watch of ConfigMaps, the ConfigMaps are sent
+// to the watches in the order they are added, updated or deleted.
+type ConfigMapServer struct {
+	*httptest.Server
+
+	mu              sync.Mutex
+	requests        []string
+	resourceVersion int
+	events          chan watch.Event
+}
+
+// NewConfigMapServer returns a new started ConfigMapServer.
+func NewConfigMapServer() *ConfigMapServer {
+	s := &ConfigMapServer{events: make(chan watch.Event, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// Config returns the rest.Config of the server.
+func (s *ConfigMapServer) Config() *rest.Config {
+	return &rest.Config{Host: s.URL}
+}
+
+// Requests returns the paths and the queries of the received requests.
+func (s *ConfigMapServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.requests...)
+}
+
+// Add sends the added ConfigMap to the watches.
+func (s *ConfigMapServer) Add(cm *corev1.ConfigMap) {
+	s.send(watch.Added, cm)
+}
+
+// Update sends the updated ConfigMap to the watches.
+func (s *ConfigMapServer) Update(cm *corev1.ConfigMap) {
+	s.send(watch.Modified, cm)
+}
+
+// Delete sends the deleted ConfigMap to the watches.
+func (s *ConfigMapServer) Delete(cm *corev1.ConfigMap) {
+	s.send(watch.Deleted, cm)
+}
+
+func (s *ConfigMapServer) send(typ watch.EventType, cm *corev1.ConfigMap) {
+	s.mu.Lock()
+	s.resourceVersion++
+	cm = cm.DeepCopy()
+	cm.APIVersion = "v1"
+	cm.Kind = "ConfigMap"
+	cm.ResourceVersion = strconv.Itoa(s.resourceVersion + 1)
+	s.mu.Unlock()
+
+	s.events <- watch.Event{Type: typ, Object: cm}
+}
+
+func (s *ConfigMapServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path+"?"+r.URL.RawQuery)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("watch") != "true" {
+		_ = json.NewEncoder(w).Encode(&corev1.ConfigMapList{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMapList"},
+			ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-s.events:
+			raw, err := json.Marshal(event.Object)
+			if err != nil {
+				return
+			}
+			if err := encoder.Encode(&metav1.WatchEvent{Type: string(event.Type), Object: runtime.RawExtension{Raw: raw}}); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}
+}
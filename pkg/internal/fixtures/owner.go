@@ -0,0 +1,31 @@
+// Package fixtures contains the webhooks and the fakes which are shared by the tests of the packages.
+package fixtures
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+// OwnerWebhook defaults the node name and requires an owner label on pods.
+type OwnerWebhook struct {
+	webhook.ValidatingWebhook
+}
+
+// Mutate implements the Mutator interface.
+func (w *OwnerWebhook) Mutate(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+	obj.(*corev1.Pod).Spec.NodeName = "jin"
+	return admission.Allowed("").WithWarnings("node name defaulted")
+}
+
+// ValidateCreate implements the Validator interface.
+func (w *OwnerWebhook) ValidateCreate(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+	if obj.(*corev1.Pod).Labels["owner"] == "" {
+		return admission.Denied("owner label is required")
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,13 @@
+package opa_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOPA(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OPA Test Suite")
+}
@@ -0,0 +1,305 @@
+// Package opa evaluates Rego policies of the Open Policy Agent in-process as generic validating webhooks.
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+const (
+	// DefaultQuery is the default query of the deny set.
+	DefaultQuery = "data.kubernetes.admission.deny"
+
+	regoSuffix     = ".rego"
+	regoTestSuffix = "_test.rego"
+)
+
+// ensure OPAValidator implements webhook.Validator and webhook.LazyDecoder
+var (
+	_ webhook.Validator   = &OPAValidator{}
+	_ webhook.LazyDecoder = &OPAValidator{}
+)
+
+// OPAValidator is a webhook.Validator which evaluates Rego policies in-process. The input of the policies is the
+// AdmissionReview of the request, the request is denied if the deny set of the query is not empty.
+// The elements of the deny set are either messages or objects with a 'msg' and an optional 'field', e.g.
+// '{"msg": "at most 10 replicas are allowed", "field": "spec.replicas"}', which are mapped into field-level causes.
+// The policies are reloaded at runtime from a directory or a ConfigMap, invalid policies are ignored and the last
+// valid policies are kept.
+type OPAValidator struct {
+	query    string
+	prepared atomic.Pointer[rego.PreparedEvalQuery]
+}
+
+// NewOPAValidator returns a new OPAValidator which evaluates the query, the DefaultQuery is used if it is empty.
+func NewOPAValidator(query string) *OPAValidator {
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	return &OPAValidator{query: query}
+}
+
+// Loaded returns true once valid policies were loaded.
+func (v *OPAValidator) Loaded() bool {
+	return v.prepared.Load() != nil
+}
+
+// Load compiles the Rego modules by their file names and replaces the current policies if they are valid.
+func (v *OPAValidator) Load(ctx context.Context, modules map[string]string) error {
+	if len(modules) == 0 {
+		return fmt.Errorf("no Rego modules found")
+	}
+
+	opts := []func(*rego.Rego){rego.Query(v.query)}
+	for name, module := range modules {
+		opts = append(opts, rego.Module(name, module))
+	}
+
+	prepared, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to compile Rego modules: %w", err)
+	}
+
+	v.prepared.Store(&prepared)
+	return nil
+}
+
+// LoadDir loads all Rego modules of the directory, test modules ending with '_test.rego' are ignored.
+func (v *OPAValidator) LoadDir(ctx context.Context, dir string) error {
+	modules, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+
+	return v.Load(ctx, modules)
+}
+
+// WatchDir loads the Rego modules of the directory and reloads them in the given interval whenever they change.
+// The initial load must succeed, later invalid policies are ignored. The directory is watched until the context is
+// done.
+func (v *OPAValidator) WatchDir(ctx context.Context, dir string, interval time.Duration) error {
+	modules, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+	if err := v.Load(ctx, modules); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := readDir(dir)
+			if err != nil {
+				log.Log.Error(err, "unable to read Rego modules", "dir", dir)
+				continue
+			}
+			if reflect.DeepEqual(current, modules) {
+				continue
+			}
+
+			modules = current
+			if err := v.Load(ctx, modules); err != nil {
+				log.Log.Error(err, "ignoring Rego modules of directory", "dir", dir)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// WatchConfigMap loads the Rego modules from all keys ending with '.rego' in the data of the ConfigMap whenever it
+// changes. Invalid policies are ignored and the last valid policies are kept if the ConfigMap is removed.
+// Only the ConfigMap itself is listed and watched, it is watched once the manager is started.
+func (v *OPAValidator) WatchConfigMap(mgr manager.Manager, configMap types.NamespacedName) error {
+	return webhook.WatchConfigMap(mgr, configMap, func(cm *corev1.ConfigMap) {
+		if cm == nil {
+			log.Log.Info("ConfigMap removed, keeping current Rego modules", "configmap", configMap.String())
+			return
+		}
+
+		modules := map[string]string{}
+		for key, value := range cm.Data {
+			if strings.HasSuffix(key, regoSuffix) && !strings.HasSuffix(key, regoTestSuffix) {
+				modules[key] = value
+			}
+		}
+
+		if err := v.Load(context.Background(), modules); err != nil {
+			log.Log.Error(err, "ignoring Rego modules of ConfigMap", "configmap", configMap.String())
+		}
+	})
+}
+
+// DecodesLazily implements the webhook.LazyDecoder interface, the policies are evaluated against the raw objects.
+func (v *OPAValidator) DecodesLazily() {}
+
+// ValidateCreate implements the webhook.Validator interface.
+func (v *OPAValidator) ValidateCreate(ctx context.Context, req admission.Request, _ runtime.Object) admission.Response {
+	return v.validate(ctx, req)
+}
+
+// ValidateUpdate implements the webhook.Validator interface.
+func (v *OPAValidator) ValidateUpdate(ctx context.Context, req admission.Request, _ runtime.Object, _ runtime.Object) admission.Response {
+	return v.validate(ctx, req)
+}
+
+// ValidateDelete implements the webhook.Validator interface.
+func (v *OPAValidator) ValidateDelete(ctx context.Context, req admission.Request, _ runtime.Object) admission.Response {
+	return v.validate(ctx, req)
+}
+
+func (v *OPAValidator) validate(ctx context.Context, req admission.Request) admission.Response {
+	prepared := v.prepared.Load()
+	if prepared == nil {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("no Rego policies loaded"))
+	}
+
+	input, err := reviewInput(req)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	results, err := prepared.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("failed to evaluate Rego policies: %w", err))
+	}
+
+	var causes []metav1.StatusCause
+	for _, result := range results {
+		for _, expression := range result.Expressions {
+			denials, ok := expression.Value.([]interface{})
+			if !ok {
+				return admission.Errored(http.StatusInternalServerError,
+					fmt.Errorf("query %s must evaluate to a set but evaluates to %T", v.query, expression.Value))
+			}
+
+			for _, denial := range denials {
+				causes = append(causes, toCause(denial))
+			}
+		}
+	}
+
+	if len(causes) == 0 {
+		return admission.Allowed("")
+	}
+
+	return denied(causes)
+}
+
+// reviewInput returns the AdmissionReview of the request as generic JSON.
+func reviewInput(req admission.Request) (interface{}, error) {
+	raw, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Request:  &req.AdmissionRequest,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var input interface{}
+	err = json.Unmarshal(raw, &input)
+	return input, err
+}
+
+// toCause maps an element of the deny set to a status cause.
+func toCause(denial interface{}) metav1.StatusCause {
+	cause := metav1.StatusCause{Type: metav1.CauseTypeFieldValueInvalid}
+	switch d := denial.(type) {
+	case string:
+		cause.Message = d
+	case map[string]interface{}:
+		if msg, ok := d["msg"].(string); ok {
+			cause.Message = msg
+		}
+		if field, ok := d["field"].(string); ok {
+			cause.Field = field
+		}
+	}
+
+	if cause.Message == "" {
+		raw, _ := json.Marshal(denial)
+		cause.Message = string(raw)
+	}
+
+	return cause
+}
+
+// denied returns a denial with the causes sorted by their field and message.
+func denied(causes []metav1.StatusCause) admission.Response {
+	sort.Slice(causes, func(i, j int) bool {
+		if causes[i].Field != causes[j].Field {
+			return causes[i].Field < causes[j].Field
+		}
+		return causes[i].Message < causes[j].Message
+	})
+
+	messages := make([]string, 0, len(causes))
+	for _, cause := range causes {
+		if cause.Field != "" {
+			messages = append(messages, cause.Field+": "+cause.Message)
+		} else {
+			messages = append(messages, cause.Message)
+		}
+	}
+
+	resp := admission.Denied(strings.Join(messages, ", "))
+	resp.Result.Reason = metav1.StatusReasonInvalid
+	resp.Result.Code = http.StatusUnprocessableEntity
+	resp.Result.Details = &metav1.StatusDetails{Causes: causes}
+	return resp
+}
+
+// readDir reads the Rego modules of the directory by their file names.
+func readDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := map[string]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, regoSuffix) || strings.HasSuffix(name, regoTestSuffix) {
+			continue
+		}
+
+		module, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		modules[name] = string(module)
+	}
+
+	return modules, nil
+}
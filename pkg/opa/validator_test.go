@@ -0,0 +1,163 @@
+package opa_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	manager "github.com/snorwin/k8s-generic-webhook/pkg/mocks/manager"
+	"go.uber.org/mock/gomock"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/internal/fixtures"
+	"github.com/snorwin/k8s-generic-webhook/pkg/opa"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+const (
+	replicasPolicy = `package kubernetes.admission
+
+deny contains {"msg": sprintf("at most %d replicas are allowed", [input.request.object.spec.replicas - 1]), "field": "spec.replicas"} if {
+	input.request.object.spec.replicas > 10
+}
+`
+	namePolicy = `package kubernetes.admission
+
+deny contains "name foo is not allowed" if {
+	input.request.name == "foo"
+}
+`
+)
+
+var _ = Describe("OPAValidator", func() {
+	var (
+		validator  *opa.OPAValidator
+		deployment *appsv1.Deployment
+	)
+	BeforeEach(func() {
+		validator = opa.NewOPAValidator("")
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "bar", Name: "bar"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](3)},
+		}
+	})
+	handle := func() admission.Response {
+		h, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&appsv1.Deployment{}).
+			Build(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Create(deployment).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		return resp
+	}
+	It("should fail if no policies are loaded", func() {
+		Ω(validator.Loaded()).Should(BeFalse())
+		Ω(handle()).Should(webhooktest.BeDenied())
+	})
+	It("should evaluate policies", func() {
+		Ω(validator.Load(context.TODO(), map[string]string{
+			"replicas.rego": replicasPolicy,
+			"name.rego":     namePolicy,
+		})).Should(Succeed())
+		Ω(validator.Loaded()).Should(BeTrue())
+		Ω(handle()).Should(webhooktest.BeAllowed())
+
+		deployment.Name = "foo"
+		deployment.Spec.Replicas = ptr.To[int32](11)
+		resp := handle()
+		Ω(resp).Should(webhooktest.BeDeniedWithReason("name foo is not allowed, spec.replicas: at most 10 replicas are allowed"))
+		Ω(resp.Result.Code).Should(BeEquivalentTo(http.StatusUnprocessableEntity))
+		Ω(resp.Result.Reason).Should(Equal(metav1.StatusReasonInvalid))
+		Ω(resp.Result.Details.Causes).Should(ConsistOf(
+			metav1.StatusCause{Type: metav1.CauseTypeFieldValueInvalid, Message: "name foo is not allowed"},
+			metav1.StatusCause{Type: metav1.CauseTypeFieldValueInvalid, Message: "at most 10 replicas are allowed", Field: "spec.replicas"},
+		))
+	})
+	It("should keep policies if invalid policies are loaded", func() {
+		Ω(validator.Load(context.TODO(), map[string]string{"name.rego": namePolicy})).Should(Succeed())
+		Ω(validator.Load(context.TODO(), map[string]string{"name.rego": "package foo\ndeny contains"})).ShouldNot(Succeed())
+		Ω(validator.Load(context.TODO(), map[string]string{})).ShouldNot(Succeed())
+
+		deployment.Name = "foo"
+		Ω(handle()).Should(webhooktest.BeDeniedWithReason("name foo is not allowed"))
+	})
+	It("should reload policies from directory", func() {
+		dir, err := os.MkdirTemp("", "policies")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		Ω(os.WriteFile(filepath.Join(dir, "name.rego"), []byte(namePolicy), 0o600)).Should(Succeed())
+		Ω(os.WriteFile(filepath.Join(dir, "name_test.rego"), []byte("invalid"), 0o600)).Should(Succeed())
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		Ω(validator.WatchDir(ctx, dir, 10*time.Millisecond)).Should(Succeed())
+
+		deployment.Spec.Replicas = ptr.To[int32](11)
+		Ω(handle()).Should(webhooktest.BeAllowed())
+
+		Ω(os.WriteFile(filepath.Join(dir, "replicas.rego"), []byte(replicasPolicy), 0o600)).Should(Succeed())
+		Eventually(handle).Should(webhooktest.BeDenied())
+	})
+	It("should reload policies from ConfigMap", func() {
+		mock := gomock.NewController(GinkgoT())
+		defer mock.Finish()
+
+		server := fixtures.NewConfigMapServer()
+		defer server.Close()
+
+		var runnable ctrlmanager.Runnable
+		mgr := manager.NewMockManager(mock)
+		mgr.EXPECT().GetConfig().Return(server.Config()).AnyTimes()
+		mgr.EXPECT().GetHTTPClient().Return(server.Client()).AnyTimes()
+		mgr.EXPECT().Add(gomock.Any()).DoAndReturn(func(r ctrlmanager.Runnable) error {
+			runnable = r
+			return nil
+		})
+
+		Ω(validator.WatchConfigMap(mgr, types.NamespacedName{Namespace: "foo", Name: "policies"})).Should(Succeed())
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Ω(runnable.Start(ctx)).Should(Succeed())
+		}()
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "policies"},
+			Data:       map[string]string{"name.rego": namePolicy, "README.md": "foo"},
+		}
+		server.Add(cm)
+		Eventually(validator.Loaded).Should(BeTrue())
+		for _, request := range server.Requests() {
+			Ω(request).Should(HavePrefix("/api/v1/namespaces/foo/configmaps?"))
+			Ω(request).Should(ContainSubstring("fieldSelector=metadata.name%3Dpolicies"))
+		}
+
+		deployment.Spec.Replicas = ptr.To[int32](11)
+		Ω(handle()).Should(webhooktest.BeAllowed())
+
+		updated := cm.DeepCopy()
+		updated.Data["replicas.rego"] = replicasPolicy
+		server.Update(updated)
+		Eventually(handle).Should(webhooktest.BeDenied())
+
+		server.Delete(updated)
+		Consistently(handle, 50*time.Millisecond).Should(webhooktest.BeDenied())
+	})
+})
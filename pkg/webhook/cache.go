@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// index is a field index which is required by a webhook.
+type index struct {
+	obj       client.Object
+	field     string
+	extractor client.IndexerFunc
+}
+
+// registerCaches registers the required field indexes and informers of the webhook in the cache of the manager and
+// returns the informers.
+func (blder *Builder) registerCaches() ([]cache.Informer, error) {
+	if len(blder.indexes) == 0 && len(blder.informers) == 0 {
+		return nil, nil
+	}
+
+	if blder.mgr == nil {
+		return nil, fmt.Errorf("indexes and informers require a webhook which is managed by a manager")
+	}
+
+	for _, idx := range blder.indexes {
+		if err := blder.mgr.GetFieldIndexer().IndexField(context.TODO(), idx.obj, idx.field, idx.extractor); err != nil {
+			return nil, fmt.Errorf("unable to index field %q: %w", idx.field, err)
+		}
+	}
+
+	objs := append([]client.Object{}, blder.informers...)
+	for _, idx := range blder.indexes {
+		objs = append(objs, idx.obj)
+	}
+
+	var informers []cache.Informer
+	for _, obj := range objs {
+		informer, err := blder.mgr.GetCache().GetInformer(context.TODO(), obj, cache.BlockUntilSynced(false))
+		if err != nil {
+			return nil, err
+		}
+		informers = append(informers, informer)
+	}
+
+	return informers, nil
+}
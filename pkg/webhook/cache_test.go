@@ -0,0 +1,132 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	manager "github.com/snorwin/k8s-generic-webhook/pkg/mocks/manager"
+	"go.uber.org/mock/gomock"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	webhook2 "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+type fieldIndexer struct {
+	fields []string
+}
+
+func (f *fieldIndexer) IndexField(_ context.Context, _ client.Object, field string, _ client.IndexerFunc) error {
+	for _, indexed := range f.fields {
+		if indexed == field {
+			return fmt.Errorf("indexer conflict: field %q is already indexed", field)
+		}
+	}
+
+	f.fields = append(f.fields, field)
+	return nil
+}
+
+var _ = Describe("Cache", func() {
+	var (
+		mock      *gomock.Controller
+		mgr       *manager.MockManager
+		informers *informertest.FakeInformers
+		indexer   *fieldIndexer
+		checks    map[string]healthz.Checker
+	)
+	BeforeEach(func() {
+		mock = gomock.NewController(GinkgoT())
+		mgr = manager.NewMockManager(mock)
+		informers = &informertest.FakeInformers{Scheme: scheme.Scheme}
+		indexer = &fieldIndexer{}
+		checks = map[string]healthz.Checker{}
+
+		mgr.EXPECT().GetScheme().Return(scheme.Scheme).AnyTimes()
+		mgr.EXPECT().GetCache().Return(informers).AnyTimes()
+		mgr.EXPECT().GetFieldIndexer().Return(indexer).AnyTimes()
+		mgr.EXPECT().GetClient().Return(nil).AnyTimes()
+		mgr.EXPECT().GetAPIReader().Return(nil).AnyTimes()
+		mgr.EXPECT().GetEventRecorderFor(gomock.Any()).Return(nil).AnyTimes()
+		mgr.EXPECT().GetRESTMapper().Return(nil).AnyTimes()
+		mgr.EXPECT().GetLogger().Return(logr.Discard()).AnyTimes()
+		mgr.EXPECT().GetWebhookServer().Return(&webhook2.DefaultServer{}).AnyTimes()
+		mgr.EXPECT().
+			AddHealthzCheck(gomock.Any(), gomock.Any()).
+			Return(nil).
+			AnyTimes()
+		mgr.EXPECT().
+			AddReadyzCheck(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(name string, check healthz.Checker) error {
+				checks[name] = check
+				return nil
+			}).
+			AnyTimes()
+	})
+	AfterEach(func() {
+		mock.Finish()
+	})
+	It("should register indexes and informers", func() {
+		err := webhook.NewGenericWebhookManagedBy(mgr).
+			For(&corev1.Pod{}).
+			WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+				return []string{obj.(*corev1.Pod).Spec.NodeName}
+			}).
+			WithInformer(&appsv1.Deployment{}).
+			Complete(&webhook.ValidateFuncs{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(indexer.fields).Should(Equal([]string{"spec.nodeName"}))
+		Ω(checks).Should(HaveKey("webhook-validate--v1-pod-informers"))
+		check := checks["webhook-validate--v1-pod-informers"]
+
+		pods, err := informers.FakeInformerFor(context.TODO(), &corev1.Pod{})
+		Ω(err).ShouldNot(HaveOccurred())
+		deployments, err := informers.FakeInformerFor(context.TODO(), &appsv1.Deployment{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(check(&http.Request{})).ShouldNot(Succeed())
+		pods.Synced = true
+		Ω(check(&http.Request{})).ShouldNot(Succeed())
+		deployments.Synced = true
+		Ω(check(&http.Request{})).Should(Succeed())
+	})
+	It("should skip indexes and informers of already registered webhooks", func() {
+		complete := func() error {
+			return webhook.NewGenericWebhookManagedBy(mgr).
+				For(&corev1.Pod{}).
+				WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+					return []string{obj.(*corev1.Pod).Spec.NodeName}
+				}).
+				Complete(&webhook.ValidateFuncs{})
+		}
+		Ω(complete()).Should(Succeed())
+		Ω(complete()).Should(Succeed())
+		Ω(indexer.fields).Should(Equal([]string{"spec.nodeName"}))
+	})
+	It("should not add readiness check of informers without indexes and informers", func() {
+		err := webhook.NewGenericWebhookManagedBy(mgr).
+			For(&corev1.Pod{}).
+			Complete(&webhook.ValidateFuncs{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(checks).ShouldNot(HaveKey("webhook-validate--v1-pod-informers"))
+	})
+	It("should fail without manager", func() {
+		srv := webhook.NewServer(webhook.ServerOptions{})
+		err := webhook.NewGenericWebhookServedBy(srv).
+			For(&corev1.Pod{}).
+			WithInformer(&appsv1.Deployment{}).
+			Complete(&webhook.ValidateFuncs{})
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// celCostLimit is the runtime cost limit of a single CEL expression.
+	celCostLimit = 1000000
+	// celInterruptCheckFrequency is the number of iterations after which the evaluation checks for cancellation.
+	celInterruptCheckFrequency = 100
+)
+
+// ensure CELValidator implements Validator and Compiler
+var (
+	_ Validator = &CELValidator{}
+	_ Compiler  = &CELValidator{}
+)
+
+// CELRule is a validation rule of a CELValidator.
+type CELRule struct {
+	// Expression is a CEL expression which must evaluate to true, otherwise the request is denied.
+	Expression string
+	// Message is the message of the denial, default is 'failed expression: <expression>'.
+	Message string
+	// MessageExpression is a CEL expression which evaluates to the message of the denial, it takes precedence over
+	// the Message if it evaluates to a non-empty string.
+	MessageExpression string
+	// Reason is the reason of the denial, default is Invalid.
+	Reason metav1.StatusReason
+}
+
+// CELValidator is a Validator which evaluates CEL rules following the semantics of a ValidatingAdmissionPolicy.
+// The rules have access to the variables 'object' and 'oldObject' (null on create respectively on delete),
+// 'request' (the AdmissionRequest without the objects) and 'params'. The rules are compiled once by the Builder,
+// the Kubernetes specific CEL libraries of the API server are not available.
+type CELValidator struct {
+	// Rules are the validation rules, a request is denied if any of the rules is violated.
+	Rules []CELRule
+	// Params returns the value of the 'params' variable, 'params' is null if not set.
+	Params func() interface{}
+
+	programs []celProgram
+}
+
+type celProgram struct {
+	rule    CELRule
+	program cel.Program
+	message cel.Program
+}
+
+// Compile implements the Compiler interface.
+func (v *CELValidator) Compile() error {
+	env, err := newCELEnv()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	programs := make([]celProgram, 0, len(v.Rules))
+	for i, rule := range v.Rules {
+		compiled := celProgram{rule: rule}
+		if compiled.program, err = compileCEL(env, rule.Expression, cel.BoolType); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: expression %q: %w", i, rule.Expression, err))
+		}
+		if rule.MessageExpression != "" {
+			if compiled.message, err = compileCEL(env, rule.MessageExpression, cel.StringType); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: message expression %q: %w", i, rule.MessageExpression, err))
+			}
+		}
+		programs = append(programs, compiled)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to compile CEL rules: %w", errors.Join(errs...))
+	}
+
+	v.programs = programs
+	return nil
+}
+
+// ValidateCreate implements the Validator interface.
+func (v *CELValidator) ValidateCreate(ctx context.Context, req admission.Request, obj runtime.Object) admission.Response {
+	return v.validate(ctx, req, obj, nil)
+}
+
+// ValidateUpdate implements the Validator interface.
+func (v *CELValidator) ValidateUpdate(ctx context.Context, req admission.Request, obj runtime.Object, oldObj runtime.Object) admission.Response {
+	return v.validate(ctx, req, obj, oldObj)
+}
+
+// ValidateDelete implements the Validator interface.
+func (v *CELValidator) ValidateDelete(ctx context.Context, req admission.Request, obj runtime.Object) admission.Response {
+	return v.validate(ctx, req, nil, obj)
+}
+
+func (v *CELValidator) validate(ctx context.Context, req admission.Request, obj, oldObj runtime.Object) admission.Response {
+	if v.programs == nil && len(v.Rules) > 0 {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("CEL rules are not compiled"))
+	}
+
+	vars, err := celVariables(req, obj, oldObj, v.Params)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	var (
+		messages []string
+		reason   metav1.StatusReason
+	)
+	for _, p := range v.programs {
+		val, _, err := p.program.ContextEval(ctx, vars)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("expression '%s' resulted in error: %v", p.rule.Expression, err))
+			continue
+		}
+		if allowed, ok := val.Value().(bool); ok && allowed {
+			continue
+		}
+
+		if reason == "" {
+			reason = p.rule.Reason
+		}
+		messages = append(messages, p.denialMessage(ctx, vars))
+	}
+
+	if len(messages) == 0 {
+		return admission.Allowed("")
+	}
+
+	return deniedWithReason(reason, strings.Join(messages, "; "))
+}
+
+// denialMessage returns the message of the rule, the message expression takes precedence over the message.
+func (p celProgram) denialMessage(ctx context.Context, vars map[string]interface{}) string {
+	if p.message != nil {
+		if val, _, err := p.message.ContextEval(ctx, vars); err == nil {
+			if message, ok := val.Value().(string); ok && strings.TrimSpace(message) != "" {
+				return message
+			}
+		}
+	}
+
+	if p.rule.Message != "" {
+		return p.rule.Message
+	}
+
+	return "failed expression: " + p.rule.Expression
+}
+
+// newCELEnv returns the CEL environment of the rules and mutations.
+func newCELEnv(opts ...cel.EnvOption) (*cel.Env, error) {
+	return cel.NewEnv(append(opts,
+		cel.Variable("object", cel.DynType),
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("params", cel.DynType),
+		ext.Strings(),
+		ext.Sets(),
+		ext.Lists(),
+		ext.Math(),
+		ext.Encoders(),
+	)...)
+}
+
+// compileCEL compiles the expression and verifies that its output type is assignable to one of the output types.
+func compileCEL(env *cel.Env, expression string, outputs ...*cel.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	assignable := ast.OutputType().IsExactType(cel.DynType)
+	for _, output := range outputs {
+		assignable = assignable || output.IsAssignableType(ast.OutputType())
+	}
+	if !assignable {
+		return nil, fmt.Errorf("must evaluate to %s but evaluates to %s", outputs[0], ast.OutputType())
+	}
+
+	return env.Program(ast,
+		cel.CostLimit(celCostLimit),
+		cel.InterruptCheckFrequency(celInterruptCheckFrequency),
+	)
+}
+
+// celVariables returns the variables of the CEL expressions.
+func celVariables(req admission.Request, obj, oldObj runtime.Object, params func() interface{}) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+
+	for name, o := range map[string]runtime.Object{"object": obj, "oldObject": oldObj} {
+		vars[name] = nil
+		if o == nil {
+			continue
+		}
+
+		value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+
+	request := req.AdmissionRequest
+	request.Object, request.OldObject = runtime.RawExtension{}, runtime.RawExtension{}
+	value, err := toJSONValue(request)
+	if err != nil {
+		return nil, err
+	}
+	vars["request"] = value
+
+	vars["params"] = nil
+	if params != nil {
+		if vars["params"], err = toJSONValue(params()); err != nil {
+			return nil, err
+		}
+	}
+
+	return vars, nil
+}
+
+// toJSONValue converts the value to its generic JSON representation.
+func toJSONValue(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var converted interface{}
+	err = json.Unmarshal(raw, &converted)
+	return converted, err
+}
+
+// deniedWithReason returns a denial with the reason and the corresponding status code.
+func deniedWithReason(reason metav1.StatusReason, message string) admission.Response {
+	if reason == "" {
+		reason = metav1.StatusReasonInvalid
+	}
+
+	code := int32(http.StatusUnprocessableEntity)
+	switch reason {
+	case metav1.StatusReasonForbidden:
+		code = http.StatusForbidden
+	case metav1.StatusReasonUnauthorized:
+		code = http.StatusUnauthorized
+	case metav1.StatusReasonRequestEntityTooLarge:
+		code = http.StatusRequestEntityTooLarge
+	}
+
+	resp := admission.Denied(message)
+	resp.Result.Reason = reason
+	resp.Result.Code = code
+	return resp
+}
@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	jsonpatchapply "github.com/evanphx/json-patch/v5"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"gomodules.xyz/jsonpatch/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ensure CELMutator implements Mutator and Compiler
+var (
+	_ Mutator  = &CELMutator{}
+	_ Compiler = &CELMutator{}
+)
+
+const (
+	// celObjectType is the type of apply configurations, nested fields are declared as e.g. 'Object.spec{...}'.
+	celObjectType = "Object"
+	// celJSONPatchType is the type of JSON patch operations.
+	celJSONPatchType = "JSONPatch"
+)
+
+// CELPatchType is the type of the patch produced by a CELMutation.
+type CELPatchType string
+
+const (
+	// CELApplyConfiguration is the type of mutations whose expression evaluates to an 'Object{...}' or a map which is
+	// merged into the object.
+	CELApplyConfiguration CELPatchType = "ApplyConfiguration"
+	// CELJSONPatch is the type of mutations whose expression evaluates to a list of 'JSONPatch{...}' or of maps.
+	CELJSONPatch CELPatchType = "JSONPatch"
+)
+
+// CELMutation is a mutation of a CELMutator.
+type CELMutation struct {
+	// PatchType is the type of the patch produced by the expression, default is CELApplyConfiguration.
+	PatchType CELPatchType
+	// Expression is a CEL expression which evaluates either to an apply configuration which is merged into the object,
+	// e.g. 'Object{metadata: Object.metadata{labels: {"foo": "bar"}}}', or to a list of JSON patch operations, e.g.
+	// '[JSONPatch{op: "add", path: "/metadata/labels/foo", value: "bar"}]'. Maps are accepted instead of the typed
+	// declarations as well.
+	Expression string
+}
+
+// CELMutator is a Mutator which evaluates CEL mutations in the style of a MutatingAdmissionPolicy, the expressions of
+// a policy use the same 'Object{...}' and 'JSONPatch{...}' declarations and the 'jsonpatch.escapeKey' function.
+// The mutations are applied in order and each mutation sees the object as mutated by the previous mutations.
+// The expressions have access to the same variables as the rules of a CELValidator. Unlike a policy, the fields of the
+// declarations are not type-checked against the schema of the object and apply configurations are merged by a
+// strategic merge patch for typed objects, i.e. lists are merged by their merge keys, and by a JSON merge patch for
+// unstructured objects, instead of by server-side apply.
+type CELMutator struct {
+	// Mutations are the mutations which are applied in order.
+	Mutations []CELMutation
+	// Params returns the value of the 'params' variable, 'params' is null if not set.
+	Params func() interface{}
+
+	programs []cel.Program
+}
+
+// Compile implements the Compiler interface.
+func (m *CELMutator) Compile() error {
+	registry, err := types.NewRegistry()
+	if err != nil {
+		return err
+	}
+	env, err := newCELEnv(
+		cel.CustomTypeProvider(&celMutationTypes{Registry: registry}),
+		cel.Function("jsonpatch.escapeKey",
+			cel.Overload("jsonpatch_escape_key_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(key ref.Val) ref.Val {
+					return types.String(strings.NewReplacer("~", "~0", "/", "~1").Replace(string(key.(types.String))))
+				}),
+			),
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	programs := make([]cel.Program, 0, len(m.Mutations))
+	for i, mutation := range m.Mutations {
+		var outputs []*cel.Type
+		switch mutation.PatchType {
+		case CELApplyConfiguration, "":
+			outputs = []*cel.Type{cel.ObjectType(celObjectType), cel.MapType(cel.StringType, cel.DynType)}
+		case CELJSONPatch:
+			outputs = []*cel.Type{cel.ListType(cel.ObjectType(celJSONPatchType)), cel.ListType(cel.DynType)}
+		default:
+			errs = append(errs, fmt.Errorf("mutation %d: invalid patch type %q", i, mutation.PatchType))
+			continue
+		}
+
+		program, err := compileCEL(env, mutation.Expression, outputs...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mutation %d: expression %q: %w", i, mutation.Expression, err))
+		}
+		programs = append(programs, program)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to compile CEL mutations: %w", errors.Join(errs...))
+	}
+
+	m.programs = programs
+	return nil
+}
+
+// Mutate implements the Mutator interface.
+func (m *CELMutator) Mutate(ctx context.Context, req admission.Request, obj runtime.Object) admission.Response {
+	if m.programs == nil && len(m.Mutations) > 0 {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("CEL mutations are not compiled"))
+	}
+
+	for i, program := range m.programs {
+		if err := m.mutate(ctx, req, obj, m.Mutations[i].PatchType, program); err != nil {
+			return admission.Errored(http.StatusInternalServerError,
+				fmt.Errorf("mutation %d: expression '%s' resulted in error: %w", i, m.Mutations[i].Expression, err))
+		}
+	}
+
+	return admission.Allowed("")
+}
+
+// mutate evaluates the program and applies the resulting patch to the object.
+func (m *CELMutator) mutate(ctx context.Context, req admission.Request, obj runtime.Object, typ CELPatchType, program cel.Program) error {
+	vars, err := celVariables(req, obj, req.OldObject.Object, m.Params)
+	if err != nil {
+		return err
+	}
+
+	val, _, err := program.ContextEval(ctx, vars)
+	if err != nil {
+		return err
+	}
+
+	value, err := val.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return err
+	}
+	patch, err := protojson.Marshal(value.(*structpb.Value))
+	if err != nil {
+		return err
+	}
+
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch typ {
+	case CELJSONPatch:
+		var patches []jsonpatch.JsonPatchOperation
+		if err := json.Unmarshal(patch, &patches); err != nil {
+			return err
+		}
+		patched, err = ApplyJSONPatch(original, patches)
+	default:
+		if _, ok := obj.(runtime.Unstructured); ok {
+			patched, err = jsonpatchapply.MergePatch(original, patch)
+		} else {
+			patched, err = strategicpatch.StrategicMergePatch(original, patch, obj)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return resetInto(patched, obj)
+}
+
+// celMutationTypes provides the 'Object' and 'JSONPatch' types of the declarations of the mutations in addition to the
+// types of the registry. The values of the declarations are maps, the fields of 'Object' declarations are dynamic.
+type celMutationTypes struct {
+	*types.Registry
+}
+
+// FindStructType implements the types.Provider interface.
+func (t *celMutationTypes) FindStructType(structType string) (*types.Type, bool) {
+	if isCELMutationType(structType) {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+
+	return t.Registry.FindStructType(structType)
+}
+
+// FindStructFieldNames implements the types.Provider interface.
+func (t *celMutationTypes) FindStructFieldNames(structType string) ([]string, bool) {
+	switch {
+	case structType == celJSONPatchType:
+		return []string{"op", "path", "from", "value"}, true
+	case isCELMutationType(structType):
+		return []string{}, true
+	}
+
+	return t.Registry.FindStructFieldNames(structType)
+}
+
+// FindStructFieldType implements the types.Provider interface.
+func (t *celMutationTypes) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	switch {
+	case structType == celJSONPatchType:
+		switch fieldName {
+		case "op", "path", "from":
+			return &types.FieldType{Type: types.StringType}, true
+		case "value":
+			return &types.FieldType{Type: types.DynType}, true
+		}
+		return nil, false
+	case isCELMutationType(structType):
+		return &types.FieldType{Type: types.DynType}, true
+	}
+
+	return t.Registry.FindStructFieldType(structType, fieldName)
+}
+
+// NewValue implements the types.Provider interface.
+func (t *celMutationTypes) NewValue(structType string, fields map[string]ref.Val) ref.Val {
+	if !isCELMutationType(structType) {
+		return t.Registry.NewValue(structType, fields)
+	}
+
+	values := make(map[ref.Val]ref.Val, len(fields))
+	for name, value := range fields {
+		values[types.String(name)] = value
+	}
+	return types.NewRefValMap(t.Registry, values)
+}
+
+// isCELMutationType returns true if the type is the 'JSONPatch' type, the 'Object' type or a nested 'Object' type.
+func isCELMutationType(structType string) bool {
+	return structType == celJSONPatchType || structType == celObjectType || strings.HasPrefix(structType, celObjectType+".")
+}
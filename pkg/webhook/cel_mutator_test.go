@@ -0,0 +1,146 @@
+package webhook_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("CELMutator", func() {
+	var (
+		pod *corev1.Pod
+	)
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", Labels: map[string]string{"app": "bar"}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "main", Image: "busybox"},
+				{Name: "sidecar", Image: "envoy"},
+			}},
+		}
+	})
+	build := func(m *webhook.CELMutator) admission.Handler {
+		_, h, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			Build(m)
+		Ω(err).ShouldNot(HaveOccurred())
+		return h
+	}
+	mutate := func(h admission.Handler) *corev1.Pod {
+		resp, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+
+		patched, err := webhooktest.ApplyPatch(pod, resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		return patched
+	}
+	It("should merge apply configurations", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{{
+			Expression: `{
+				"metadata": {"labels": {"team": params.team}},
+				"spec": {"containers": [{"name": "sidecar", "image": "envoy:" + params.version}]}
+			}`,
+		}}, Params: func() interface{} {
+			return map[string]string{"team": "platform", "version": "1.0"}
+		}})
+
+		patched := mutate(h)
+		Ω(patched.Labels).Should(Equal(map[string]string{"app": "bar", "team": "platform"}))
+		Ω(patched.Spec.Containers).Should(HaveLen(2))
+		Ω(patched.Spec.Containers[0].Image).Should(Equal("busybox"))
+		Ω(patched.Spec.Containers[1].Image).Should(Equal("envoy:1.0"))
+	})
+	It("should apply JSON patches", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{{
+			PatchType:  webhook.CELJSONPatch,
+			Expression: `[{"op": "add", "path": "/metadata/annotations", "value": {"owner": object.metadata.labels.app}}]`,
+		}}})
+
+		patched := mutate(h)
+		Ω(patched.Annotations).Should(Equal(map[string]string{"owner": "bar"}))
+	})
+	It("should merge typed apply configurations", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{{
+			Expression: `Object{
+				metadata: Object.metadata{labels: {"team": "platform"}},
+				spec: Object.spec{containers: [Object.spec.containers{name: "sidecar", image: "envoy:1.0"}]}
+			}`,
+		}}})
+
+		patched := mutate(h)
+		Ω(patched.Labels).Should(Equal(map[string]string{"app": "bar", "team": "platform"}))
+		Ω(patched.Spec.Containers).Should(HaveLen(2))
+		Ω(patched.Spec.Containers[1].Image).Should(Equal("envoy:1.0"))
+	})
+	It("should apply typed JSON patches", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{{
+			PatchType: webhook.CELJSONPatch,
+			Expression: `[
+				JSONPatch{op: "add", path: "/metadata/annotations", value: {}},
+				JSONPatch{op: "add", path: "/metadata/annotations/" + jsonpatch.escapeKey("example.com/owner"), value: "platform"}
+			]`,
+		}}})
+
+		patched := mutate(h)
+		Ω(patched.Annotations).Should(Equal(map[string]string{"example.com/owner": "platform"}))
+	})
+	It("should apply mutations in order", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{
+			{Expression: `{"metadata": {"labels": {"tier": "web"}}}`},
+			{
+				PatchType:  webhook.CELJSONPatch,
+				Expression: `[{"op": "add", "path": "/metadata/labels/copy", "value": object.metadata.labels.tier}]`,
+			},
+		}})
+
+		patched := mutate(h)
+		Ω(patched.Labels).Should(HaveKeyWithValue("copy", "web"))
+	})
+	It("should not patch if nothing changed", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{{
+			Expression: `{"metadata": {"labels": {"app": "bar"}}}`,
+		}}})
+
+		resp, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+		Ω(resp.Patches).Should(BeEmpty())
+	})
+	It("should deny requests if the evaluation fails", func() {
+		h := build(&webhook.CELMutator{Mutations: []webhook.CELMutation{{
+			PatchType:  webhook.CELJSONPatch,
+			Expression: `[{"op": "replace", "path": "/spec/unknown", "value": 1}]`,
+		}}})
+
+		resp, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+	})
+	It("should fail to build with invalid mutations", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			Build(&webhook.CELMutator{Mutations: []webhook.CELMutation{
+				{Expression: `{"metadata": `},
+				{Expression: `[1, 2]`},
+				{PatchType: webhook.CELJSONPatch, Expression: `{"op": "add"}`},
+				{PatchType: "foo", Expression: `{}`},
+				{Expression: `Object.spec{}`},
+				{PatchType: webhook.CELJSONPatch, Expression: `[JSONPatch{foo: "add"}]`},
+			}})
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring("mutation 0"))
+		Ω(err.Error()).Should(ContainSubstring("mutation 1"))
+		Ω(err.Error()).Should(ContainSubstring("mutation 2"))
+		Ω(err.Error()).Should(ContainSubstring(`mutation 3: invalid patch type "foo"`))
+		Ω(err.Error()).Should(ContainSubstring("mutation 4"))
+		Ω(err.Error()).Should(ContainSubstring("mutation 5"))
+	})
+})
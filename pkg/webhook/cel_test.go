@@ -0,0 +1,126 @@
+package webhook_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("CEL", func() {
+	var (
+		deployment *appsv1.Deployment
+	)
+	BeforeEach(func() {
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", Labels: map[string]string{"app": "bar"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](3)},
+		}
+	})
+	build := func(v *webhook.CELValidator) admission.Handler {
+		h, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&appsv1.Deployment{}).
+			Build(v)
+		Ω(err).ShouldNot(HaveOccurred())
+		return h
+	}
+	It("should allow requests which satisfy all rules", func() {
+		h := build(&webhook.CELValidator{Rules: []webhook.CELRule{
+			{Expression: "object.spec.replicas <= 10"},
+			{Expression: "'app' in object.metadata.labels"},
+			{Expression: "request.operation == 'CREATE' && oldObject == null"},
+		}})
+
+		resp, err := webhooktest.Create(deployment).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should deny requests which violate rules", func() {
+		h := build(&webhook.CELValidator{Rules: []webhook.CELRule{
+			{Expression: "object.spec.replicas <= 2"},
+			{Expression: "object.metadata.name != 'bar'", Message: "bar is not allowed"},
+			{Expression: "true"},
+		}})
+
+		resp, err := webhooktest.Create(deployment).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDeniedWithReason("failed expression: object.spec.replicas <= 2; bar is not allowed"))
+		Ω(resp.Result.Reason).Should(Equal(metav1.StatusReasonInvalid))
+		Ω(resp.Result.Code).Should(BeEquivalentTo(http.StatusUnprocessableEntity))
+	})
+	It("should use message expression and reason", func() {
+		h := build(&webhook.CELValidator{Rules: []webhook.CELRule{{
+			Expression:        "object.spec.replicas <= 2",
+			MessageExpression: "'replicas must be at most 2 but is ' + string(object.spec.replicas)",
+			Reason:            metav1.StatusReasonForbidden,
+		}}})
+
+		resp, err := webhooktest.Create(deployment).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDeniedWithReason("replicas must be at most 2 but is 3"))
+		Ω(resp.Result.Reason).Should(Equal(metav1.StatusReasonForbidden))
+		Ω(resp.Result.Code).Should(BeEquivalentTo(http.StatusForbidden))
+	})
+	It("should provide old object and params", func() {
+		h := build(&webhook.CELValidator{
+			Rules: []webhook.CELRule{
+				{Expression: "object.spec.replicas - oldObject.spec.replicas <= params.maxScaleUp"},
+			},
+			Params: func() interface{} {
+				return map[string]int{"maxScaleUp": 1}
+			},
+		})
+
+		old := deployment.DeepCopy()
+		old.Spec.Replicas = ptr.To[int32](2)
+		resp, err := webhooktest.Update(deployment, old).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+
+		old.Spec.Replicas = ptr.To[int32](1)
+		resp, err = webhooktest.Update(deployment, old).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+	})
+	It("should provide object on delete as old object", func() {
+		h := build(&webhook.CELValidator{Rules: []webhook.CELRule{
+			{Expression: "object == null && oldObject.metadata.name == 'bar'"},
+		}})
+
+		resp, err := webhooktest.Delete(deployment).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should deny requests if evaluation fails", func() {
+		h := build(&webhook.CELValidator{Rules: []webhook.CELRule{
+			{Expression: "object.spec.unknown == 1"},
+		}})
+
+		resp, err := webhooktest.Create(deployment).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+		Ω(resp.Result.Message).Should(ContainSubstring("expression 'object.spec.unknown == 1' resulted in error"))
+	})
+	It("should fail to build with invalid rules", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&appsv1.Deployment{}).
+			Build(&webhook.CELValidator{Rules: []webhook.CELRule{
+				{Expression: "object.spec.replicas <="},
+				{Expression: "'foo'"},
+				{Expression: "true", MessageExpression: "1"},
+			}})
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(`rule 0: expression "object.spec.replicas <="`))
+		Ω(err.Error()).Should(ContainSubstring(`rule 1: expression "'foo'": must evaluate to bool but evaluates to string`))
+		Ω(err.Error()).Should(ContainSubstring(`rule 2: message expression "1": must evaluate to string but evaluates to int`))
+	})
+})
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// selfTestUID is the UID of the AdmissionRequests of the self-test, the handler allows such requests without invoking
+// the webhook in order to short-circuit all side effects. It is generated per process, hence it cannot be guessed by
+// other clients of the webhook.
+var selfTestUID = types.UID("generic-webhook-self-test-" + string(uuid.NewUUID()))
+
+// checkRegistry is implemented by manager.Manager and Server.
+type checkRegistry interface {
+	AddHealthzCheck(name string, check healthz.Checker) error
+	AddReadyzCheck(name string, check healthz.Checker) error
+}
+
+// registerChecks adds the liveness and readiness checks of the webhook registered on the path:
+//   - 'webhook-<path>' (liveness and readiness) sends an AdmissionReview round-trip to the path
+//   - 'webhook-<path>-certificate' (readiness) verifies that the TLS certificate is loaded and not expired
+//   - 'webhook-<path>-informers' (readiness) verifies that the required informers are synced, it is added whenever the
+//     webhook requires informers
+func (blder *Builder) registerChecks(path string, informers []cache.Informer) error {
+	var registry checkRegistry = blder.server
+	if blder.mgr != nil {
+		registry = blder.mgr
+	}
+
+	name := "webhook-" + strings.TrimPrefix(path, "/")
+	server := blder.webhookServer()
+
+	if err := registry.AddHealthzCheck(name, selfTestChecker(server, path)); err != nil {
+		return err
+	}
+	if err := registry.AddReadyzCheck(name, selfTestChecker(server, path)); err != nil {
+		return err
+	}
+
+	if opts, ok := serverOptions(server); ok {
+		if err := registry.AddReadyzCheck(name+"-certificate", certificateChecker(opts)); err != nil {
+			return err
+		}
+	}
+
+	if len(informers) > 0 {
+		if err := registry.AddReadyzCheck(name+"-informers", informersChecker(path, informers)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selfTestChecker sends an AdmissionReview of the self-test through the multiplexer of the server to the path and
+// verifies the response.
+func selfTestChecker(server ctrlwebhook.Server, path string) healthz.Checker {
+	return func(_ *http.Request) error {
+		body, err := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+			Request:  &admissionv1.AdmissionRequest{UID: selfTestUID},
+		})
+		if err != nil {
+			return err
+		}
+
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.WebhookMux().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			return fmt.Errorf("self-test of webhook %s failed with status code %d", path, recorder.Code)
+		}
+
+		review := &admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), review); err != nil {
+			return fmt.Errorf("self-test of webhook %s failed: %w", path, err)
+		}
+		if review.Response == nil || review.Response.UID != selfTestUID || !review.Response.Allowed {
+			return fmt.Errorf("self-test of webhook %s failed with unexpected response", path)
+		}
+
+		return nil
+	}
+}
+
+// certificateChecker verifies that the TLS certificate of the server is loaded, either by the GetCertificate of the
+// TLS options or from the certificate file, and that it is not expired.
+func certificateChecker(opts ctrlwebhook.Options) healthz.Checker {
+	return func(_ *http.Request) error {
+		cfg := &tls.Config{}
+		for _, op := range opts.TLSOpts {
+			op(cfg)
+		}
+
+		var raw []byte
+		if cfg.GetCertificate != nil {
+			crt, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+			if err != nil {
+				return err
+			}
+			if crt == nil || len(crt.Certificate) == 0 {
+				return fmt.Errorf("no TLS certificate loaded")
+			}
+			raw = crt.Certificate[0]
+		} else {
+			dir, name := opts.CertDir, opts.CertName
+			if dir == "" {
+				dir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+			}
+			if name == "" {
+				name = "tls.crt"
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return err
+			}
+			block, _ := pem.Decode(data)
+			if block == nil || block.Type != "CERTIFICATE" {
+				return fmt.Errorf("no PEM encoded certificate found in %s", filepath.Join(dir, name))
+			}
+			raw = block.Bytes
+		}
+
+		crt, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if now.Before(crt.NotBefore) {
+			return fmt.Errorf("TLS certificate is not valid before %s", crt.NotBefore)
+		}
+		if now.After(crt.NotAfter) {
+			return fmt.Errorf("TLS certificate expired at %s", crt.NotAfter)
+		}
+
+		return nil
+	}
+}
+
+// informersChecker verifies that the informers are synced.
+func informersChecker(path string, informers []cache.Informer) healthz.Checker {
+	return func(_ *http.Request) error {
+		for _, informer := range informers {
+			if !informer.HasSynced() {
+				return fmt.Errorf("informers of webhook %s are not synced", path)
+			}
+		}
+
+		return nil
+	}
+}
+
+// serverOptions returns the options of the webhook server if it is a default webhook server.
+func serverOptions(server ctrlwebhook.Server) (ctrlwebhook.Options, bool) {
+	if srv, ok := server.(*Server); ok {
+		server = srv.Server
+	}
+
+	if srv, ok := server.(*ctrlwebhook.DefaultServer); ok {
+		return srv.Options, true
+	}
+
+	return ctrlwebhook.Options{}, false
+}
@@ -0,0 +1,159 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/cert"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+var _ = Describe("Checks", func() {
+	var (
+		dir   string
+		srv   *webhook.Server
+		calls int
+		wh    webhook.Validator
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "certs")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		calls = 0
+		wh = &webhook.ValidateFuncs{
+			CreateFunc: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+				calls++
+				return admission.Denied("denied")
+			},
+		}
+	})
+	AfterEach(func() {
+		Ω(os.RemoveAll(dir)).Should(Succeed())
+	})
+	probe := func(path string) int {
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+	complete := func(opts ctrlwebhook.Options) {
+		srv = webhook.NewServer(webhook.ServerOptions{Options: opts})
+		err := webhook.NewGenericWebhookServedBy(srv).
+			For(&corev1.Pod{}).
+			Complete(wh)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+	writeCert := func(crt []byte) {
+		Ω(os.WriteFile(filepath.Join(dir, "tls.crt"), crt, 0o600)).Should(Succeed())
+	}
+	It("should pass the self-test without invoking the webhook", func() {
+		complete(ctrlwebhook.Options{CertDir: dir})
+
+		Ω(probe("/healthz/webhook-validate--v1-pod")).Should(Equal(http.StatusOK))
+		Ω(probe("/readyz/webhook-validate--v1-pod")).Should(Equal(http.StatusOK))
+		Ω(calls).Should(BeZero())
+	})
+	It("should invoke the webhook for requests of other UIDs", func() {
+		complete(ctrlwebhook.Options{CertDir: dir})
+
+		body, err := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "generic-webhook-self-test",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)},
+			},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		req := httptest.NewRequest(http.MethodPost, "/validate--v1-pod", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		srv.WebhookMux().ServeHTTP(recorder, req)
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+
+		review := &admissionv1.AdmissionReview{}
+		Ω(json.Unmarshal(recorder.Body.Bytes(), review)).Should(Succeed())
+		Ω(review.Response.Allowed).Should(BeFalse())
+		Ω(calls).Should(Equal(1))
+	})
+	It("should verify the certificate file", func() {
+		complete(ctrlwebhook.Options{CertDir: dir})
+		Ω(probe("/readyz/webhook-validate--v1-pod-certificate")).Should(Equal(http.StatusInternalServerError))
+
+		writeCert([]byte("foo"))
+		Ω(probe("/readyz/webhook-validate--v1-pod-certificate")).Should(Equal(http.StatusInternalServerError))
+
+		writeCert(expiredCert())
+		Ω(probe("/readyz/webhook-validate--v1-pod-certificate")).Should(Equal(http.StatusInternalServerError))
+
+		crt, _, err := cert.GenerateSelfSignedCertKey("localhost", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		writeCert(crt)
+		Ω(probe("/readyz/webhook-validate--v1-pod-certificate")).Should(Equal(http.StatusOK))
+	})
+	It("should verify the certificate of the TLS options", func() {
+		crt, key, err := cert.GenerateSelfSignedCertKey("localhost", nil, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		pair, err := tls.X509KeyPair(crt, key)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		complete(ctrlwebhook.Options{
+			CertDir: dir,
+			TLSOpts: []func(*tls.Config){func(cfg *tls.Config) {
+				cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+					return &pair, nil
+				}
+			}},
+		})
+		Ω(probe("/readyz/webhook-validate--v1-pod-certificate")).Should(Equal(http.StatusOK))
+	})
+	It("should not add checks twice", func() {
+		complete(ctrlwebhook.Options{CertDir: dir})
+		err := webhook.NewGenericWebhookServedBy(srv).
+			For(&corev1.Pod{}).
+			Complete(wh)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+})
+
+func expiredCert() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     time.Now().Add(-time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	informerscorev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigProvider provides the configuration of webhooks, it is injected into webhooks which implement the
+// ConfigInjector interface.
+type ConfigProvider interface {
+	// Loaded returns true once a valid configuration was loaded from the source.
+	Loaded() bool
+}
+
+// ensure ConfigSource implements ConfigProvider
+var _ ConfigProvider = &ConfigSource[struct{}]{}
+
+// ConfigSource holds a typed configuration which is reloaded at runtime from a ConfigMap or from a local file.
+// The configuration is decoded from YAML or JSON, fields which are unknown to T are rejected.
+type ConfigSource[T any] struct {
+	validate func(T) error
+	value    atomic.Pointer[T]
+	loaded   atomic.Bool
+}
+
+// NewConfigSource returns a new ConfigSource which holds the defaults until a configuration is loaded.
+// The validate function is optional, configurations which are not valid are rejected.
+func NewConfigSource[T any](defaults T, validate func(T) error) *ConfigSource[T] {
+	s := &ConfigSource[T]{validate: validate}
+	s.value.Store(&defaults)
+
+	return s
+}
+
+// Get returns the current configuration.
+func (s *ConfigSource[T]) Get() T {
+	return *s.value.Load()
+}
+
+// Loaded implements the ConfigProvider interface.
+func (s *ConfigSource[T]) Loaded() bool {
+	return s.loaded.Load()
+}
+
+// Load decodes and validates the configuration and replaces the current configuration if it is valid.
+func (s *ConfigSource[T]) Load(data []byte) error {
+	var value T
+	if err := yaml.UnmarshalStrict(data, &value); err != nil {
+		return fmt.Errorf("unable to decode configuration: %w", err)
+	}
+
+	if s.validate != nil {
+		if err := s.validate(value); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+
+	s.value.Store(&value)
+	s.loaded.Store(true)
+	return nil
+}
+
+// WatchConfigMap loads the configuration from the value of the key in the data of the ConfigMap whenever it changes.
+// Invalid configurations are ignored and the last valid configuration is kept if the ConfigMap or the key is removed.
+// Only the ConfigMap itself is listed and watched, it is watched once the manager is started.
+func (s *ConfigSource[T]) WatchConfigMap(mgr manager.Manager, configMap types.NamespacedName, key string) error {
+	return WatchConfigMap(mgr, configMap, func(cm *corev1.ConfigMap) {
+		value, found := configMapValue(cm, key)
+		if !found {
+			log.Log.Info("configuration not found in ConfigMap, keeping current configuration", "configmap", configMap.String(), "key", key)
+			return
+		}
+
+		if err := s.Load([]byte(value)); err != nil {
+			log.Log.Error(err, "ignoring configuration of ConfigMap", "configmap", configMap.String(), "key", key)
+		}
+	})
+}
+
+// configMapValue returns the value of the key in the data of the ConfigMap, it is not found if the ConfigMap is nil.
+func configMapValue(cm *corev1.ConfigMap, key string) (string, bool) {
+	if cm == nil {
+		return "", false
+	}
+
+	value, ok := cm.Data[key]
+	return value, ok
+}
+
+// WatchConfigMap calls the update function with the ConfigMap whenever it is added, updated or deleted, the ConfigMap
+// is nil if it is deleted. The ConfigMap is watched by an informer which lists and watches only the ConfigMap itself,
+// the informer is started by the manager on all replicas and stopped with it. It is the shared helper of the
+// WatchConfigMap functions of the sources which are reloaded from a ConfigMap, e.g. ConfigSource.WatchConfigMap.
+func WatchConfigMap(mgr manager.Manager, configMap types.NamespacedName, update func(cm *corev1.ConfigMap)) error {
+	clientset, err := kubernetes.NewForConfigAndClient(mgr.GetConfig(), mgr.GetHTTPClient())
+	if err != nil {
+		return err
+	}
+
+	selector := fields.OneTermEqualSelector("metadata.name", configMap.Name).String()
+	informer := informerscorev1.NewFilteredConfigMapInformer(clientset, configMap.Namespace, 0, toolscache.Indexers{}, func(opts *metav1.ListOptions) {
+		opts.FieldSelector = selector
+	})
+
+	handle := func(obj interface{}, deleted bool) {
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok || cm.Namespace != configMap.Namespace || cm.Name != configMap.Name {
+			return
+		}
+
+		if deleted {
+			update(nil)
+		} else {
+			update(cm)
+		}
+	}
+
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handle(obj, false)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			handle(obj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			handle(obj, true)
+		},
+	}); err != nil {
+		return err
+	}
+
+	return mgr.Add(&informerRunnable{informer: informer})
+}
+
+// informerRunnable runs an informer until the manager is stopped, regardless of the leader election.
+type informerRunnable struct {
+	informer toolscache.SharedIndexInformer
+}
+
+// Start implements the manager.Runnable interface.
+func (r *informerRunnable) Start(ctx context.Context) error {
+	r.informer.Run(ctx.Done())
+	return nil
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface.
+func (r *informerRunnable) NeedLeaderElection() bool {
+	return false
+}
+
+// WatchFile loads the configuration from the local file and reloads it in the given interval whenever the content of
+// the file changes, e.g. if it is a mounted ConfigMap. The initial load must succeed, later invalid configurations are
+// ignored. The file is watched until the context is done.
+func (s *ConfigSource[T]) WatchFile(ctx context.Context, path string, interval time.Duration) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Load(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := os.ReadFile(path)
+			if err != nil {
+				log.Log.Error(err, "unable to read configuration file", "path", path)
+				continue
+			}
+			if bytes.Equal(current, data) {
+				continue
+			}
+
+			data = current
+			if err := s.Load(data); err != nil {
+				log.Log.Error(err, "ignoring configuration of file", "path", path)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	manager "github.com/snorwin/k8s-generic-webhook/pkg/mocks/manager"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/internal/fixtures"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+type registryConfig struct {
+	Registries  []string `json:"registries"`
+	MaxReplicas int      `json:"maxReplicas"`
+}
+
+type configuredWebhook struct {
+	webhook.ValidatingWebhook
+	webhook.InjectedConfig[registryConfig]
+}
+
+var _ = Describe("Config", func() {
+	var (
+		source *webhook.ConfigSource[registryConfig]
+	)
+	BeforeEach(func() {
+		source = webhook.NewConfigSource(registryConfig{MaxReplicas: 1}, func(c registryConfig) error {
+			if c.MaxReplicas < 0 {
+				return fmt.Errorf("maxReplicas must not be negative")
+			}
+			return nil
+		})
+	})
+	It("should hold defaults until loaded", func() {
+		Ω(source.Loaded()).Should(BeFalse())
+		Ω(source.Get()).Should(Equal(registryConfig{MaxReplicas: 1}))
+	})
+	It("should load valid configurations", func() {
+		Ω(source.Load([]byte("registries: [docker.io]\nmaxReplicas: 3"))).Should(Succeed())
+		Ω(source.Loaded()).Should(BeTrue())
+		Ω(source.Get()).Should(Equal(registryConfig{Registries: []string{"docker.io"}, MaxReplicas: 3}))
+
+		Ω(source.Load([]byte(`{"registries": ["quay.io"]}`))).Should(Succeed())
+		Ω(source.Get()).Should(Equal(registryConfig{Registries: []string{"quay.io"}}))
+	})
+	It("should reject invalid configurations", func() {
+		Ω(source.Load([]byte("maxReplicas: -1"))).ShouldNot(Succeed())
+		Ω(source.Load([]byte("unknown: foo"))).ShouldNot(Succeed())
+		Ω(source.Load([]byte("maxReplicas: [foo]"))).ShouldNot(Succeed())
+		Ω(source.Loaded()).Should(BeFalse())
+		Ω(source.Get()).Should(Equal(registryConfig{MaxReplicas: 1}))
+	})
+	It("should reload configuration from ConfigMap", func() {
+		mock := gomock.NewController(GinkgoT())
+		defer mock.Finish()
+
+		server := fixtures.NewConfigMapServer()
+		defer server.Close()
+
+		var runnable ctrlmanager.Runnable
+		mgr := manager.NewMockManager(mock)
+		mgr.EXPECT().GetConfig().Return(server.Config()).AnyTimes()
+		mgr.EXPECT().GetHTTPClient().Return(server.Client()).AnyTimes()
+		mgr.EXPECT().Add(gomock.Any()).DoAndReturn(func(r ctrlmanager.Runnable) error {
+			runnable = r
+			return nil
+		})
+
+		err := source.WatchConfigMap(mgr, types.NamespacedName{Namespace: "foo", Name: "bar"}, "config.yaml")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(runnable.(ctrlmanager.LeaderElectionRunnable).NeedLeaderElection()).Should(BeFalse())
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		go func() {
+			defer GinkgoRecover()
+			Ω(runnable.Start(ctx)).Should(Succeed())
+		}()
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Data:       map[string]string{"config.yaml": "maxReplicas: 2"},
+		}
+		server.Add(cm)
+		Eventually(func() int { return source.Get().MaxReplicas }).Should(Equal(2))
+		Ω(server.Requests()).Should(ContainElement(HavePrefix("/api/v1/namespaces/foo/configmaps?")))
+		for _, request := range server.Requests() {
+			Ω(request).Should(ContainSubstring("fieldSelector=metadata.name%3Dbar"))
+		}
+
+		server.Add(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "other"},
+			Data:       map[string]string{"config.yaml": "maxReplicas: 5"},
+		})
+		Consistently(func() int { return source.Get().MaxReplicas }, 50*time.Millisecond).Should(Equal(2))
+
+		updated := cm.DeepCopy()
+		updated.Data["config.yaml"] = "maxReplicas: -1"
+		server.Update(updated)
+		Consistently(func() int { return source.Get().MaxReplicas }, 50*time.Millisecond).Should(Equal(2))
+
+		updated.Data["config.yaml"] = "maxReplicas: 4"
+		server.Update(updated)
+		Eventually(func() int { return source.Get().MaxReplicas }).Should(Equal(4))
+
+		server.Delete(updated)
+		Consistently(func() int { return source.Get().MaxReplicas }, 50*time.Millisecond).Should(Equal(4))
+	})
+	It("should reload configuration from file", func() {
+		dir, err := os.MkdirTemp("", "config")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "config.yaml")
+		Ω(os.WriteFile(path, []byte("maxReplicas: 2"), 0o600)).Should(Succeed())
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		Ω(source.WatchFile(ctx, path, 10*time.Millisecond)).Should(Succeed())
+		Ω(source.Get().MaxReplicas).Should(Equal(2))
+
+		Ω(os.WriteFile(path, []byte("maxReplicas: -1"), 0o600)).Should(Succeed())
+		Consistently(func() int { return source.Get().MaxReplicas }, 50*time.Millisecond).Should(Equal(2))
+
+		Ω(os.WriteFile(path, []byte("maxReplicas: 3"), 0o600)).Should(Succeed())
+		Eventually(func() int { return source.Get().MaxReplicas }).Should(Equal(3))
+	})
+	It("should fail if the initial configuration file is not valid", func() {
+		dir, err := os.MkdirTemp("", "config")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "config.yaml")
+		Ω(os.WriteFile(path, []byte("maxReplicas: -1"), 0o600)).Should(Succeed())
+		Ω(source.WatchFile(context.TODO(), path, time.Second)).ShouldNot(Succeed())
+		Ω(source.WatchFile(context.TODO(), filepath.Join(path, "missing"), time.Second)).ShouldNot(Succeed())
+	})
+	It("should inject configuration", func() {
+		wh := &configuredWebhook{}
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			WithConfig(source).
+			Build(wh)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(wh.Config).Should(BeIdenticalTo(source))
+	})
+	It("should fail to inject configuration of other type", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			WithConfig(webhook.NewConfigSource("", nil)).
+			Build(&configuredWebhook{})
+		Ω(err).Should(HaveOccurred())
+	})
+	It("should fail without configuration", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			Build(&configuredWebhook{})
+		Ω(err).Should(HaveOccurred())
+	})
+})
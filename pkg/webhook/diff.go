@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+// DiffCategory is the category of the paths changed by an update.
+type DiffCategory string
+
+const (
+	// DiffSpec is the category of the changed paths which are neither status nor metadata, e.g. 'spec' or 'data'.
+	DiffSpec DiffCategory = "spec"
+	// DiffStatus is the category of the changed paths of the status.
+	DiffStatus DiffCategory = "status"
+	// DiffMetadata is the category of the changed paths of the metadata which are not part of other categories.
+	DiffMetadata DiffCategory = "metadata"
+	// DiffLabels is the category of the changed paths of the labels.
+	DiffLabels DiffCategory = "labels"
+	// DiffAnnotations is the category of the changed paths of the annotations.
+	DiffAnnotations DiffCategory = "annotations"
+	// DiffFinalizers is the category of the changed paths of the finalizers.
+	DiffFinalizers DiffCategory = "finalizers"
+	// DiffOwnerReferences is the category of the changed paths of the owner references.
+	DiffOwnerReferences DiffCategory = "ownerReferences"
+	// DiffDeletion is the category of the changed paths of the deletion timestamp and grace period.
+	DiffDeletion DiffCategory = "deletion"
+)
+
+// serverManagedPaths are the paths of the metadata which are managed by the API server and not part of a Diff.
+var serverManagedPaths = []string{"/metadata/managedFields", "/metadata/resourceVersion", "/metadata/generation"}
+
+// Diff is the structured diff between the old and the new object of an update.
+type Diff struct {
+	// Paths are the sorted JSON pointers of the changed paths by their category.
+	Paths map[DiffCategory][]string
+}
+
+// UpdateDiff returns the Diff between the old and the new object of the request which is handled with the context.
+// The Diff is computed once per request from the raw objects, it is nil if the request is not an update. The paths
+// of the metadata which are managed by the API server, e.g. the managed fields, are not part of the Diff.
+func UpdateDiff(ctx context.Context) (*Diff, error) {
+	return Objects(ctx).updateDiff()
+}
+
+// Changed returns true if any path of the categories changed.
+func (d *Diff) Changed(categories ...DiffCategory) bool {
+	if d == nil {
+		return false
+	}
+
+	for _, category := range categories {
+		if len(d.Paths[category]) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OnlyChanged returns true if no paths of other categories changed, i.e. also if nothing changed.
+func (d *Diff) OnlyChanged(categories ...DiffCategory) bool {
+	if d == nil {
+		return true
+	}
+
+	for category, paths := range d.Paths {
+		if len(paths) > 0 && !containsCategory(categories, category) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// updateDiff computes the Diff of the objects once.
+func (o *LazyObjects) updateDiff() (*Diff, error) {
+	o.diffOnce.Do(func() {
+		if o.newObject.empty() || o.oldObject.empty() {
+			return
+		}
+
+		var newRaw, oldRaw []byte
+		if newRaw, o.diffErr = o.newObject.marshal(); o.diffErr != nil {
+			return
+		}
+		if oldRaw, o.diffErr = o.oldObject.marshal(); o.diffErr != nil {
+			return
+		}
+
+		var patches []jsonpatch.JsonPatchOperation
+		if patches, o.diffErr = jsonpatch.CreatePatch(oldRaw, newRaw); o.diffErr == nil {
+			o.diff = newDiff(patches)
+		}
+	})
+
+	return o.diff, o.diffErr
+}
+
+// marshal returns the raw object, the object is marshalled if the request doesn't contain the raw object.
+func (l *lazyObject) marshal() ([]byte, error) {
+	if len(l.raw.Raw) > 0 {
+		return l.raw.Raw, nil
+	}
+
+	return json.Marshal(l.raw.Object)
+}
+
+// newDiff returns the Diff of the paths changed by the patches.
+func newDiff(patches []jsonpatch.JsonPatchOperation) *Diff {
+	diff := &Diff{Paths: map[DiffCategory][]string{}}
+	for _, patch := range patches {
+		if isServerManaged(patch.Path) {
+			continue
+		}
+
+		category := diffCategory(patch.Path)
+		diff.Paths[category] = append(diff.Paths[category], patch.Path)
+	}
+
+	for _, paths := range diff.Paths {
+		sort.Strings(paths)
+	}
+
+	return diff
+}
+
+// diffCategory returns the category of the JSON pointer.
+func diffCategory(path string) DiffCategory {
+	switch {
+	case hasPathPrefix(path, "/metadata/labels"):
+		return DiffLabels
+	case hasPathPrefix(path, "/metadata/annotations"):
+		return DiffAnnotations
+	case hasPathPrefix(path, "/metadata/finalizers"):
+		return DiffFinalizers
+	case hasPathPrefix(path, "/metadata/ownerReferences"):
+		return DiffOwnerReferences
+	case hasPathPrefix(path, "/metadata/deletionTimestamp"), hasPathPrefix(path, "/metadata/deletionGracePeriodSeconds"):
+		return DiffDeletion
+	case hasPathPrefix(path, "/metadata"):
+		return DiffMetadata
+	case hasPathPrefix(path, "/status"):
+		return DiffStatus
+	default:
+		return DiffSpec
+	}
+}
+
+// isServerManaged returns true if the JSON pointer belongs to a path managed by the API server.
+func isServerManaged(path string) bool {
+	for _, prefix := range serverManagedPaths {
+		if hasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasPathPrefix returns true if the JSON pointer is the prefix or a path below it.
+func hasPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// containsCategory returns true if the categories contain the category.
+func containsCategory(categories []DiffCategory, category DiffCategory) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+
+	return false
+}
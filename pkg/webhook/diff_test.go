@@ -0,0 +1,124 @@
+package webhook_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("UpdateDiff", func() {
+	var (
+		deployment *appsv1.Deployment
+		diff       *webhook.Diff
+		invoked    bool
+		validator  *webhook.ValidateFuncs
+	)
+	BeforeEach(func() {
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "foo",
+				Name:            "bar",
+				ResourceVersion: "1",
+				Labels:          map[string]string{"app": "bar"},
+			},
+			Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](1)},
+		}
+		diff, invoked = nil, false
+		validator = &webhook.ValidateFuncs{
+			CreateFunc: func(ctx context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+				var err error
+				diff, err = webhook.UpdateDiff(ctx)
+				Ω(err).ShouldNot(HaveOccurred())
+				return admission.Allowed("")
+			},
+			UpdateFunc: func(ctx context.Context, _ admission.Request, _ runtime.Object, _ runtime.Object) admission.Response {
+				var err error
+				diff, err = webhook.UpdateDiff(ctx)
+				Ω(err).ShouldNot(HaveOccurred())
+				invoked = true
+				return admission.Denied("")
+			},
+		}
+	})
+	It("should classify the changed paths", func() {
+		updated := deployment.DeepCopy()
+		updated.ResourceVersion = "2"
+		updated.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+		updated.Labels["app.kubernetes.io/name"] = "bar"
+		updated.Annotations = map[string]string{"foo": "bar"}
+		updated.Finalizers = []string{"example.com/cleanup"}
+		updated.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "foo", UID: "1"}}
+		updated.GenerateName = "bar-"
+		updated.Spec.Replicas = ptr.To[int32](2)
+		updated.Status.Replicas = 2
+
+		_, err := webhooktest.Update(updated, deployment).Validate(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(diff).ShouldNot(BeNil())
+		Ω(diff.Paths).Should(Equal(map[webhook.DiffCategory][]string{
+			webhook.DiffSpec:            {"/spec/replicas"},
+			webhook.DiffStatus:          {"/status/replicas"},
+			webhook.DiffMetadata:        {"/metadata/generateName"},
+			webhook.DiffLabels:          {"/metadata/labels/app.kubernetes.io~1name"},
+			webhook.DiffAnnotations:     {"/metadata/annotations"},
+			webhook.DiffFinalizers:      {"/metadata/finalizers"},
+			webhook.DiffOwnerReferences: {"/metadata/ownerReferences"},
+		}))
+		Ω(diff.Changed(webhook.DiffSpec)).Should(BeTrue())
+		Ω(diff.OnlyChanged(webhook.DiffStatus, webhook.DiffMetadata)).Should(BeFalse())
+	})
+	It("should be nil if the request is not an update", func() {
+		_, err := webhooktest.Create(deployment).Validate(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(diff).Should(BeNil())
+		Ω(diff.Changed(webhook.DiffSpec)).Should(BeFalse())
+	})
+	It("should skip the validator if only ignored paths changed", func() {
+		validating, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&appsv1.Deployment{}).
+			WithIgnoredChanges(webhook.DiffStatus, webhook.DiffMetadata).
+			Build(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		updated := deployment.DeepCopy()
+		updated.ResourceVersion = "2"
+		updated.Status.Replicas = 2
+		resp, err := webhooktest.Update(updated, deployment).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+		Ω(invoked).Should(BeFalse())
+
+		updated.Labels["foo"] = "bar"
+		resp, err = webhooktest.Update(updated, deployment).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+		Ω(invoked).Should(BeTrue())
+		Ω(diff.Changed(webhook.DiffLabels)).Should(BeTrue())
+	})
+	It("should not skip the validator if the finalizers changed", func() {
+		validating, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&appsv1.Deployment{}).
+			WithIgnoredChanges(webhook.DiffStatus, webhook.DiffMetadata).
+			Build(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		updated := deployment.DeepCopy()
+		updated.Finalizers = []string{"example.com/cleanup"}
+		resp, err := webhooktest.Update(updated, deployment).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+		Ω(invoked).Should(BeTrue())
+		Ω(diff.OnlyChanged(webhook.DiffFinalizers)).Should(BeTrue())
+	})
+})
@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// EnforcementMode defines how the denials of a validating webhook are enforced.
+type EnforcementMode string
+
+const (
+	// Enforce denies the request, this is the default.
+	Enforce EnforcementMode = "Enforce"
+	// Warn allows the request with a warning carrying the denial message.
+	Warn EnforcementMode = "Warn"
+	// Audit allows the request with an audit annotation carrying the denial message.
+	Audit EnforcementMode = "Audit"
+)
+
+// unenforcedDenials counts the denials which were not enforced because of the enforcement mode.
+var unenforcedDenials = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "generic_webhook_unenforced_denials_total",
+	Help: "Total number of denials which were not enforced per webhook and enforcement mode.",
+}, []string{"webhook", "mode"})
+
+func init() {
+	metrics.Registry.MustRegister(unenforcedDenials)
+}
+
+// ParseEnforcementMode parses the case-insensitive EnforcementMode.
+func ParseEnforcementMode(value string) (EnforcementMode, error) {
+	for _, mode := range []EnforcementMode{Enforce, Warn, Audit} {
+		if strings.EqualFold(value, string(mode)) {
+			return mode, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid enforcement mode %q", value)
+}
+
+// EnforcementSwitch holds the EnforcementMode of webhooks, the mode can be switched at runtime.
+type EnforcementSwitch struct {
+	initial EnforcementMode
+	mode    atomic.Value
+}
+
+// NewEnforcementSwitch returns a new EnforcementSwitch with the given initial mode, an error is returned if the mode
+// is invalid.
+func NewEnforcementSwitch(mode EnforcementMode) (*EnforcementSwitch, error) {
+	parsed, err := ParseEnforcementMode(string(mode))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &EnforcementSwitch{initial: parsed}
+	s.mode.Store(parsed)
+
+	return s, nil
+}
+
+// Get returns the current EnforcementMode.
+func (s *EnforcementSwitch) Get() EnforcementMode {
+	return s.mode.Load().(EnforcementMode)
+}
+
+// Set switches the EnforcementMode, an error is returned if the mode is invalid.
+func (s *EnforcementSwitch) Set(mode EnforcementMode) error {
+	parsed, err := ParseEnforcementMode(string(mode))
+	if err != nil {
+		return err
+	}
+
+	s.mode.Store(parsed)
+	return nil
+}
+
+// WatchConfigMap switches the EnforcementMode according to the value of the key in the data of the ConfigMap.
+// The initial mode is restored if the ConfigMap or the key is removed, invalid values are ignored. Only the ConfigMap
+// itself is listed and watched, it is watched once the manager is started.
+func (s *EnforcementSwitch) WatchConfigMap(mgr manager.Manager, configMap types.NamespacedName, key string) error {
+	return WatchConfigMap(mgr, configMap, func(cm *corev1.ConfigMap) {
+		value, found := configMapValue(cm, key)
+		if !found {
+			s.mode.Store(s.initial)
+			return
+		}
+
+		mode, err := ParseEnforcementMode(value)
+		if err != nil {
+			log.Log.Error(err, "ignoring enforcement mode of ConfigMap", "configmap", configMap.String(), "key", key)
+			return
+		}
+		s.mode.Store(mode)
+	})
+}
+
+// EnforcementOverrides defines the keys of the label of namespaces and of the annotation of objects which override
+// the EnforcementMode of a webhook, e.g. a namespace labeled with 'webhook.example.com/enforcement: warn' downgrades
+// denials to warnings for all objects in this namespace. The annotation of an object can only tighten the mode of its
+// namespace, unless the request is made by one of the users or groups which are allowed to loosen it. For updates the
+// stricter mode of the old and the new object applies.
+type EnforcementOverrides struct {
+	// NamespaceLabel is the key of the label of the namespace of an object, ignored if empty.
+	NamespaceLabel string
+	// ObjectAnnotation is the key of the annotation of an object, ignored if empty.
+	ObjectAnnotation string
+	// LooseningUsers are the names of the users whose requests may loosen the mode by the annotation of an object.
+	LooseningUsers []string
+	// LooseningGroups are the groups of the users whose requests may loosen the mode by the annotation of an object.
+	LooseningGroups []string
+}
+
+// loosening returns true if the user of the request is allowed to loosen the mode by the annotation of an object.
+func (o *EnforcementOverrides) loosening(req admission.Request) bool {
+	if contains(o.LooseningUsers, req.UserInfo.Username) {
+		return true
+	}
+	for _, group := range o.LooseningGroups {
+		if contains(req.UserInfo.Groups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// strictness orders the enforcement modes from the loosest to the strictest.
+var strictness = map[EnforcementMode]int{Audit: 0, Warn: 1, Enforce: 2}
+
+// enforce applies the EnforcementMode to the denials of the validator, errors of the webhook itself are not affected.
+func (h *handler) enforce(ctx context.Context, req admission.Request, resp admission.Response) admission.Response {
+	if resp.Allowed || (h.enforcement == nil && h.enforcementOverrides == nil) || !isDenial(resp) {
+		return resp
+	}
+
+	mode := h.enforcementMode(ctx, req)
+	if mode == Enforce {
+		return resp
+	}
+
+	message := fmt.Sprintf("request would be denied by webhook %s", h.name)
+	if resp.Result != nil && resp.Result.Message != "" {
+		message = resp.Result.Message
+	}
+
+	allowed := admission.Allowed("")
+	allowed.Warnings = resp.Warnings
+	switch mode {
+	case Warn:
+		allowed.Warnings = append(allowed.Warnings, message)
+	case Audit:
+		allowed.AuditAnnotations = map[string]string{
+			"enforcement-mode": string(mode),
+			"denial":           message,
+		}
+	default:
+		return resp
+	}
+
+	log.FromContext(ctx).Info("denial not enforced", "mode", mode, "message", message)
+	unenforcedDenials.WithLabelValues(h.name, string(mode)).Inc()
+
+	return allowed
+}
+
+// isDenial returns true if the response is a denial of the validator, e.g. by admission.Denied, and not an error, e.g.
+// by admission.Errored which doesn't set a reason.
+func isDenial(resp admission.Response) bool {
+	if resp.Result == nil || resp.Result.Reason != "" {
+		return true
+	}
+
+	switch resp.Result.Code {
+	case 0, http.StatusForbidden, http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
+
+// enforcementMode resolves the EnforcementMode of the request from the annotations of the objects, the label of the
+// namespace and the global mode.
+func (h *handler) enforcementMode(ctx context.Context, req admission.Request) EnforcementMode {
+	mode := Enforce
+	if h.enforcement != nil {
+		mode = h.enforcement.Get()
+	}
+
+	overrides := h.enforcementOverrides
+	if overrides == nil {
+		return mode
+	}
+
+	if overrides.NamespaceLabel != "" && req.Namespace != "" && h.namespaces != nil {
+		mode = h.namespaceEnforcementMode(ctx, req.Namespace, mode)
+	}
+
+	if overrides.ObjectAnnotation == "" {
+		return mode
+	}
+
+	// objects without the annotation inherit the mode of the namespace, on updates the strictest mode applies in order
+	// to prevent that a denial is circumvented by adding the annotation in the same request. Since a new object is
+	// created with its annotation, only the allowed users may loosen the mode.
+	loosening := overrides.loosening(req)
+	var resolved EnforcementMode
+	for _, raw := range []runtime.RawExtension{req.Object, req.OldObject} {
+		metadata, err := partialObjectMetadata(raw)
+		if err != nil || metadata == nil {
+			continue
+		}
+
+		objMode := mode
+		if value, ok := metadata.Annotations[overrides.ObjectAnnotation]; ok {
+			if parsed, err := ParseEnforcementMode(value); err != nil {
+				log.FromContext(ctx).Error(err, "ignoring enforcement mode of object", "annotation", overrides.ObjectAnnotation)
+			} else if strictness[parsed] > strictness[mode] || loosening {
+				objMode = parsed
+			}
+		}
+
+		if resolved == "" || strictness[objMode] > strictness[resolved] {
+			resolved = objMode
+		}
+	}
+
+	if resolved == "" {
+		return mode
+	}
+
+	return resolved
+}
+
+// namespaceEnforcementMode returns the EnforcementMode of the label of the namespace or the given mode.
+func (h *handler) namespaceEnforcementMode(ctx context.Context, name string, mode EnforcementMode) EnforcementMode {
+	namespace := &corev1.Namespace{}
+	if err := h.namespaces.Get(ctx, types.NamespacedName{Name: name}, namespace); err != nil {
+		log.FromContext(ctx).Error(err, "unable to get namespace for enforcement mode", "namespace", name)
+		return mode
+	}
+
+	value, ok := namespace.Labels[h.enforcementOverrides.NamespaceLabel]
+	if !ok {
+		return mode
+	}
+
+	parsed, err := ParseEnforcementMode(value)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "ignoring enforcement mode of namespace", "namespace", name)
+		return mode
+	}
+
+	return parsed
+}
@@ -0,0 +1,309 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	manager "github.com/snorwin/k8s-generic-webhook/pkg/mocks/manager"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/internal/fixtures"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Enforcement", func() {
+	var (
+		pod       *corev1.Pod
+		validator webhook.Validator
+	)
+	BeforeEach(func() {
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+		validator = &webhook.ValidateFuncs{
+			CreateFunc: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+				return admission.Denied("foo is not allowed").WithWarnings("first")
+			},
+		}
+	})
+	handle := func(blder *webhook.Builder) admission.Response {
+		h, _, err := blder.For(&corev1.Pod{}).Build(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		return resp
+	}
+	Context("Modes", func() {
+		It("should parse modes", func() {
+			mode, err := webhook.ParseEnforcementMode("warn")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(mode).Should(Equal(webhook.Warn))
+			_, err = webhook.ParseEnforcementMode("foo")
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should enforce denials by default", func() {
+			resp := handle(webhook.NewGenericWebhook(scheme.Scheme))
+			Ω(resp).Should(webhooktest.BeDeniedWithReason("foo is not allowed"))
+
+			resp = handle(webhook.NewGenericWebhook(scheme.Scheme).WithEnforcement(webhook.Enforce))
+			Ω(resp).Should(webhooktest.BeDeniedWithReason("foo is not allowed"))
+		})
+		It("should downgrade denials to warnings", func() {
+			resp := handle(webhook.NewGenericWebhook(scheme.Scheme).WithEnforcement(webhook.Warn))
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp.Warnings).Should(Equal([]string{"first", "foo is not allowed"}))
+		})
+		It("should downgrade denials to audit annotations", func() {
+			resp := handle(webhook.NewGenericWebhook(scheme.Scheme).WithEnforcement(webhook.Audit))
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp.Warnings).Should(Equal([]string{"first"}))
+			Ω(resp.AuditAnnotations).Should(HaveKeyWithValue("enforcement-mode", "Audit"))
+			Ω(resp.AuditAnnotations).Should(HaveKeyWithValue("denial", "foo is not allowed"))
+		})
+		It("should fail to build with invalid mode", func() {
+			_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+				WithEnforcement("foo").
+				For(&corev1.Pod{}).
+				Build(validator)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should not downgrade server errors of the validator", func() {
+			validator = &webhook.ValidateFuncs{
+				CreateFunc: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+					return admission.Errored(http.StatusInternalServerError, fmt.Errorf("lookup failed"))
+				},
+			}
+			resp := handle(webhook.NewGenericWebhook(scheme.Scheme).WithEnforcement(webhook.Warn))
+			Ω(resp).Should(webhooktest.BeDenied())
+			Ω(resp.Result.Code).Should(BeEquivalentTo(http.StatusInternalServerError))
+		})
+		It("should not downgrade errors of the validator", func() {
+			validator = &webhook.ValidateFuncs{
+				CreateFunc: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+					return admission.Errored(http.StatusBadRequest, fmt.Errorf("malformed request"))
+				},
+			}
+			resp := handle(webhook.NewGenericWebhook(scheme.Scheme).WithEnforcement(webhook.Warn))
+			Ω(resp).Should(webhooktest.BeDenied())
+			Ω(resp.Result.Code).Should(BeEquivalentTo(http.StatusBadRequest))
+		})
+		It("should downgrade field denials", func() {
+			validator = &webhook.ValidateFuncs{
+				CreateFunc: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+					resp := admission.Denied("spec.nodeName is invalid")
+					resp.Result.Code = http.StatusUnprocessableEntity
+					resp.Result.Reason = metav1.StatusReasonInvalid
+					return resp
+				},
+			}
+			resp := handle(webhook.NewGenericWebhook(scheme.Scheme).WithEnforcement(webhook.Warn))
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp.Warnings).Should(ContainElement("spec.nodeName is invalid"))
+		})
+		It("should not downgrade decoding errors", func() {
+			h, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+				WithEnforcement(webhook.Warn).
+				For(&corev1.Pod{}).
+				Build(validator)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			req, err := webhooktest.Create(pod).Build()
+			Ω(err).ShouldNot(HaveOccurred())
+			req.Object.Raw = []byte{1, 2, 3}
+			Ω(h.Handle(context.TODO(), req)).Should(webhooktest.BeDenied())
+		})
+	})
+	Context("EnforcementSwitch", func() {
+		It("should switch mode at runtime", func() {
+			enforcement, err := webhook.NewEnforcementSwitch(webhook.Enforce)
+			Ω(err).ShouldNot(HaveOccurred())
+			blder := webhook.NewGenericWebhook(scheme.Scheme).WithEnforcementSwitch(enforcement)
+			Ω(handle(blder)).Should(webhooktest.BeDenied())
+
+			Ω(enforcement.Set(webhook.Warn)).Should(Succeed())
+			Ω(handle(blder)).Should(webhooktest.BeAllowed())
+
+			Ω(enforcement.Set("foo")).ShouldNot(Succeed())
+			Ω(enforcement.Get()).Should(Equal(webhook.Warn))
+
+			Ω(enforcement.Set("audit")).Should(Succeed())
+			Ω(enforcement.Get()).Should(Equal(webhook.Audit))
+		})
+		It("should reject invalid initial mode", func() {
+			_, err := webhook.NewEnforcementSwitch("foo")
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should switch mode by ConfigMap", func() {
+			mock := gomock.NewController(GinkgoT())
+			defer mock.Finish()
+
+			server := fixtures.NewConfigMapServer()
+			defer server.Close()
+
+			var runnable ctrlmanager.Runnable
+			mgr := manager.NewMockManager(mock)
+			mgr.EXPECT().GetConfig().Return(server.Config()).AnyTimes()
+			mgr.EXPECT().GetHTTPClient().Return(server.Client()).AnyTimes()
+			mgr.EXPECT().Add(gomock.Any()).DoAndReturn(func(r ctrlmanager.Runnable) error {
+				runnable = r
+				return nil
+			})
+
+			enforcement, err := webhook.NewEnforcementSwitch(webhook.Enforce)
+			Ω(err).ShouldNot(HaveOccurred())
+			err = enforcement.WatchConfigMap(mgr, types.NamespacedName{Namespace: "foo", Name: "bar"}, "mode")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() {
+				defer GinkgoRecover()
+				Ω(runnable.Start(ctx)).Should(Succeed())
+			}()
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+				Data:       map[string]string{"mode": "audit"},
+			}
+			server.Add(cm)
+			Eventually(enforcement.Get).Should(Equal(webhook.Audit))
+
+			other := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "other"},
+				Data:       map[string]string{"mode": "warn"},
+			}
+			server.Add(other)
+			Consistently(enforcement.Get, 50*time.Millisecond).Should(Equal(webhook.Audit))
+
+			updated := cm.DeepCopy()
+			updated.Data["mode"] = "invalid"
+			server.Update(updated)
+			Consistently(enforcement.Get, 50*time.Millisecond).Should(Equal(webhook.Audit))
+
+			updated.Data["mode"] = "Warn"
+			server.Update(updated)
+			Eventually(enforcement.Get).Should(Equal(webhook.Warn))
+
+			server.Delete(updated)
+			Eventually(enforcement.Get).Should(Equal(webhook.Enforce))
+		})
+	})
+	Context("EnforcementOverrides", func() {
+		var (
+			overrides webhook.EnforcementOverrides
+			namespace *corev1.Namespace
+		)
+		BeforeEach(func() {
+			overrides = webhook.EnforcementOverrides{
+				NamespaceLabel:   "webhook.example.com/enforcement",
+				ObjectAnnotation: "webhook.example.com/enforcement",
+			}
+			namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}
+			pod.Namespace = "bar"
+		})
+		builder := func() *webhook.Builder {
+			return webhook.NewGenericWebhook(scheme.Scheme).
+				WithClient(fake.NewClientBuilder().WithObjects(namespace).Build()).
+				WithEnforcementOverrides(overrides)
+		}
+		It("should enforce denials without labels and annotations", func() {
+			Ω(handle(builder())).Should(webhooktest.BeDeniedWithReason("foo is not allowed"))
+		})
+		It("should downgrade denials by the label of the namespace", func() {
+			namespace.Labels = map[string]string{"webhook.example.com/enforcement": "warn"}
+			resp := handle(builder())
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp).Should(webhooktest.HaveWarning("foo is not allowed"))
+		})
+		It("should enforce denials by the label of the namespace", func() {
+			namespace.Labels = map[string]string{"webhook.example.com/enforcement": "enforce"}
+			Ω(handle(builder().WithEnforcement(webhook.Audit))).Should(webhooktest.BeDenied())
+		})
+		It("should tighten the mode by the annotation of the object", func() {
+			namespace.Labels = map[string]string{"webhook.example.com/enforcement": "audit"}
+			pod.Annotations = map[string]string{"webhook.example.com/enforcement": "warn"}
+			resp := handle(builder())
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp).Should(webhooktest.HaveWarning("foo is not allowed"))
+
+			pod.Annotations = map[string]string{"webhook.example.com/enforcement": "enforce"}
+			Ω(handle(builder())).Should(webhooktest.BeDenied())
+		})
+		It("should not loosen the mode by the annotation of the object", func() {
+			namespace.Labels = map[string]string{"webhook.example.com/enforcement": "warn"}
+			pod.Annotations = map[string]string{"webhook.example.com/enforcement": "audit"}
+			resp := handle(builder())
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp).Should(webhooktest.HaveWarning("foo is not allowed"))
+			Ω(resp.AuditAnnotations).Should(BeEmpty())
+
+			namespace.Labels = nil
+			Ω(handle(builder())).Should(webhooktest.BeDenied())
+		})
+		It("should loosen the mode by the annotation of the object for allowed users", func() {
+			overrides.LooseningUsers = []string{"admin"}
+			overrides.LooseningGroups = []string{"system:masters"}
+			pod.Annotations = map[string]string{"webhook.example.com/enforcement": "audit"}
+			h, _, err := builder().For(&corev1.Pod{}).Build(validator)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			resp, err := webhooktest.Create(pod).AsUser("admin").Handle(h)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp.AuditAnnotations).Should(HaveKeyWithValue("enforcement-mode", "Audit"))
+
+			resp, err = webhooktest.Create(pod).AsUser("jane", "system:masters").Handle(h)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+
+			resp, err = webhooktest.Create(pod).AsUser("jane").Handle(h)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDenied())
+		})
+		It("should ignore invalid values", func() {
+			namespace.Labels = map[string]string{"webhook.example.com/enforcement": "invalid"}
+			pod.Annotations = map[string]string{"webhook.example.com/enforcement": "invalid"}
+			Ω(handle(builder().WithEnforcement(webhook.Warn))).Should(webhooktest.BeAllowed())
+			Ω(handle(builder())).Should(webhooktest.BeDenied())
+		})
+		It("should fall back to the global mode if the namespace does not exist", func() {
+			pod.Namespace = "other"
+			Ω(handle(builder().WithEnforcement(webhook.Warn))).Should(webhooktest.BeAllowed())
+		})
+		It("should apply the stricter annotation on updates", func() {
+			overrides.LooseningUsers = []string{"admin"}
+			validator = &webhook.ValidateFuncs{
+				UpdateFunc: func(_ context.Context, _ admission.Request, _, _ runtime.Object) admission.Response {
+					return admission.Denied("foo is not allowed")
+				},
+			}
+			h, _, err := builder().For(&corev1.Pod{}).Build(validator)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			old := pod.DeepCopy()
+			pod.Annotations = map[string]string{"webhook.example.com/enforcement": "audit"}
+			resp, err := webhooktest.Update(pod, old).AsUser("admin").Handle(h)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDenied())
+
+			old.Annotations = map[string]string{"webhook.example.com/enforcement": "warn"}
+			resp, err = webhooktest.Update(pod, old).AsUser("admin").Handle(h)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp).Should(webhooktest.HaveWarning("foo is not allowed"))
+		})
+	})
+})
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// EventReasonDenied is the reason of the Events emitted for denied requests.
+	EventReasonDenied = "AdmissionDenied"
+	// EventReasonMutated is the reason of the Events emitted for mutated requests.
+	EventReasonMutated = "AdmissionMutated"
+)
+
+// EventOptions are the options of the Events emitted by a webhook.
+type EventOptions struct {
+	// OnDenial emits a Warning Event for every denied request.
+	OnDenial bool
+	// OnMutation emits a Normal Event for every mutated request.
+	OnMutation bool
+	// Interval in which duplicate Events are suppressed, default is 1 minute.
+	Interval time.Duration
+}
+
+// eventEmitter emits the Events of a webhook and suppresses duplicates.
+type eventEmitter struct {
+	recorder record.EventRecorder
+	opts     EventOptions
+
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+func newEventEmitter(recorder record.EventRecorder, opts EventOptions) *eventEmitter {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+
+	return &eventEmitter{recorder: recorder, opts: opts, seen: map[string]time.Time{}, pruned: time.Now()}
+}
+
+// emit emits an Event for the response on the controller owner of the object, on the object itself if it already
+// exists or on its namespace. Dry-run requests are ignored.
+func (e *eventEmitter) emit(ctx context.Context, webhook string, req admission.Request, resp admission.Response) {
+	if req.DryRun != nil && *req.DryRun {
+		return
+	}
+
+	var eventType, reason, message string
+	switch {
+	case !resp.Allowed && e.opts.OnDenial:
+		eventType, reason = corev1.EventTypeWarning, EventReasonDenied
+		message = fmt.Sprintf("%s of %s denied by webhook %s", req.Operation, objectDescription(req), webhook)
+		if resp.Result != nil && resp.Result.Message != "" {
+			message += ": " + resp.Result.Message
+		}
+	case resp.Allowed && len(resp.Patches) > 0 && e.opts.OnMutation:
+		eventType, reason = corev1.EventTypeNormal, EventReasonMutated
+		message = fmt.Sprintf("%s of %s mutated by webhook %s: %s", req.Operation, objectDescription(req), webhook,
+			strings.Join(patchPaths(resp.Patches), ", "))
+	default:
+		return
+	}
+
+	target, err := eventTarget(req)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "unable to determine target of event", "webhook", webhook)
+		return
+	}
+	if target == nil {
+		return
+	}
+
+	key := strings.Join([]string{target.Kind, target.Namespace, target.Name, string(target.UID), eventType, reason, message}, "/")
+	if !e.first(key) {
+		return
+	}
+
+	e.recorder.Event(target, eventType, reason, message)
+}
+
+// first returns true if the Event with the key was not emitted within the interval. Expired keys are evicted on
+// lookup, the keys which are not looked up again are pruned at most once per interval.
+func (e *eventEmitter) first(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(e.pruned) >= e.opts.Interval {
+		for k, t := range e.seen {
+			if now.Sub(t) >= e.opts.Interval {
+				delete(e.seen, k)
+			}
+		}
+		e.pruned = now
+	}
+
+	if t, ok := e.seen[key]; ok && now.Sub(t) < e.opts.Interval {
+		return false
+	}
+
+	e.seen[key] = now
+	return true
+}
+
+// eventTarget returns the reference to the controller owner of the object, to the object itself if it already exists
+// or to its namespace. It returns nil for cluster scoped objects which don't exist yet.
+func eventTarget(req admission.Request) (*corev1.ObjectReference, error) {
+	raw := req.Object.Raw
+	if req.Operation == admissionv1.Delete || len(raw) == 0 {
+		raw = req.OldObject.Raw
+	}
+
+	obj := &metav1.PartialObjectMetadata{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+	}
+
+	if owner := metav1.GetControllerOf(obj); owner != nil {
+		return &corev1.ObjectReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Namespace:  req.Namespace,
+			Name:       owner.Name,
+			UID:        owner.UID,
+		}, nil
+	}
+
+	if req.Operation != admissionv1.Create && req.Name != "" {
+		return &corev1.ObjectReference{
+			APIVersion: metav1.GroupVersion{Group: req.Kind.Group, Version: req.Kind.Version}.String(),
+			Kind:       req.Kind.Kind,
+			Namespace:  req.Namespace,
+			Name:       req.Name,
+			UID:        obj.UID,
+		}, nil
+	}
+
+	if req.Namespace != "" {
+		return &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       req.Namespace,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// objectDescription returns the kind and the namespaced name of the object of the request, the name is omitted if the
+// object has neither a name nor a generated name yet.
+func objectDescription(req admission.Request) string {
+	name := req.Name
+	if name == "" {
+		obj := &metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(req.Object.Raw, obj); err == nil && obj.GenerateName != "" {
+			name = obj.GenerateName + "*"
+		}
+	}
+	if name == "" {
+		return req.Kind.Kind
+	}
+
+	if req.Namespace != "" {
+		name = req.Namespace + "/" + name
+	}
+
+	return req.Kind.Kind + " " + name
+}
@@ -0,0 +1,160 @@
+package webhook_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Events", func() {
+	var (
+		recorder *record.FakeRecorder
+		pod      *corev1.Pod
+		denied   webhook.Validator
+		mutated  webhook.Mutator
+	)
+	BeforeEach(func() {
+		recorder = &record.FakeRecorder{Events: make(chan string, 10), IncludeObject: true}
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"}}
+		denied = &webhook.ValidateFuncs{
+			CreateFunc: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+				return admission.Denied("bar is not allowed")
+			},
+			UpdateFunc: func(_ context.Context, _ admission.Request, _, _ runtime.Object) admission.Response {
+				return admission.Denied("bar is not allowed")
+			},
+		}
+		mutated = &webhook.MutateFunc{
+			Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+				obj.(*corev1.Pod).Labels = map[string]string{"foo": "bar"}
+				return admission.Allowed("")
+			},
+		}
+	})
+	build := func(i interface{}, opts webhook.EventOptions) (validating, mutating admission.Handler) {
+		validating, mutating, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			WithEventRecorder(recorder).
+			WithEvents(opts).
+			Build(i)
+		Ω(err).ShouldNot(HaveOccurred())
+		return validating, mutating
+	}
+	It("should emit event on the namespace for denied creates", func() {
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true})
+
+		resp, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+
+		Ω(recorder.Events).Should(Receive(And(
+			HavePrefix("Warning AdmissionDenied CREATE of Pod foo/bar denied by webhook /validate--v1-pod: bar is not allowed"),
+			HaveSuffix("involvedObject{kind=Namespace,apiVersion=v1}"),
+		)))
+	})
+	It("should emit event on the controller owner", func() {
+		pod.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "ReplicaSet",
+			Name:       "bar-123",
+			Controller: ptr.To(true),
+		}}
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true})
+
+		_, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).Should(Receive(HaveSuffix("involvedObject{kind=ReplicaSet,apiVersion=apps/v1}")))
+	})
+	It("should emit event on existing objects", func() {
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true})
+
+		_, err := webhooktest.Update(pod, pod.DeepCopy()).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).Should(Receive(HaveSuffix("involvedObject{kind=Pod,apiVersion=v1}")))
+	})
+	It("should emit event for mutated requests", func() {
+		_, h := build(mutated, webhook.EventOptions{OnMutation: true})
+
+		_, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).Should(Receive(HavePrefix("Normal AdmissionMutated CREATE of Pod foo/bar mutated by webhook /mutate--v1-pod: /metadata/labels")))
+	})
+	It("should not emit events which are not enabled", func() {
+		h, _ := build(denied, webhook.EventOptions{OnMutation: true})
+		_, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, h = build(mutated, webhook.EventOptions{OnDenial: true})
+		_, err = webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).ShouldNot(Receive())
+	})
+	It("should not emit events for dry-run requests", func() {
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true})
+
+		_, err := webhooktest.Create(pod).DryRun().Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).ShouldNot(Receive())
+	})
+	It("should suppress duplicate events", func() {
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true})
+
+		for i := 0; i < 3; i++ {
+			_, err := webhooktest.Create(pod).Handle(h)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		pod.Name = "other"
+		_, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).Should(HaveLen(2))
+	})
+	It("should emit duplicate events again after the interval", func() {
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true, Interval: 10 * time.Millisecond})
+
+		_, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recorder.Events).Should(HaveLen(1))
+
+		time.Sleep(20 * time.Millisecond)
+		_, err = webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recorder.Events).Should(HaveLen(2))
+	})
+	It("should omit the name of objects without name", func() {
+		h, _ := build(denied, webhook.EventOptions{OnDenial: true})
+
+		pod.Name = ""
+		_, err := webhooktest.Create(pod).Handle(h)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(recorder.Events).Should(Receive(HavePrefix("Warning AdmissionDenied CREATE of Pod denied by webhook /validate--v1-pod: bar is not allowed")))
+	})
+	It("should fail without event recorder", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			WithEvents(webhook.EventOptions{OnDenial: true}).
+			Build(denied)
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -6,18 +6,21 @@ import (
 	"net/http"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 // withValidationHandler create a validation handler instance
-func withValidationHandler(validator Validator, object runtime.Object, decoder admission.Decoder) admission.Handler {
+func withValidationHandler(validator Validator, object runtime.Object, decoder admission.Decoder) *handler {
 	return &handler{validator: validator, Object: object, decoder: decoder}
 }
 
 // withMutationHandler create a mutation handler instance
-func withMutationHandler(mutator Mutator, object runtime.Object, decoder admission.Decoder) admission.Handler {
+func withMutationHandler(mutator Mutator, object runtime.Object, decoder admission.Decoder) *handler {
 	return &handler{mutator: mutator, Object: object, decoder: decoder}
 }
 
@@ -31,10 +34,74 @@ type handler struct {
 	Object runtime.Object
 
 	decoder admission.Decoder
+
+	// name of the webhook, i.e. the path it is registered on
+	name string
+	// operations handled by the webhook, all operations are handled if nil
+	operations map[admissionv1.Operation]bool
+	// exclusions are the kinds which are not handled by the webhook
+	exclusions map[schema.GroupKind]bool
+	// skipSubresources allows requests of subresources without invoking the webhook
+	skipSubresources bool
+	// lazyDecoding defers the decoding of the objects to the first access by Objects
+	lazyDecoding bool
+	// ignoredChanges are the categories of the changes on which the validator is skipped
+	ignoredChanges []DiffCategory
+	// provenanceAnnotation enables recording of the patch provenance in the given annotation if set
+	provenanceAnnotation string
+	// recorder records the requests and the responses if set
+	recorder *Recorder
+	// enforcement defines how the denials of the validator are enforced, denials are enforced if not set
+	enforcement *EnforcementSwitch
+	// enforcementOverrides defines the labels and annotations which override the enforcement mode if set
+	enforcementOverrides *EnforcementOverrides
+	// namespaces is used to read the namespaces of the objects
+	namespaces client.Reader
+	// events emits Events for denied or mutated requests if set
+	events *eventEmitter
 }
 
 // Handle implements the admission.Handler interface.
 func (h *handler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.UID == selfTestUID {
+		return admission.Allowed("self-test")
+	}
+
+	// allow unhandled operations without decoding
+	if h.operations != nil && !h.operations[req.Operation] {
+		return admission.Allowed("")
+	}
+
+	// allow excluded kinds without decoding
+	if h.exclusions[schema.GroupKind{Group: req.Kind.Group, Kind: req.Kind.Kind}] {
+		return admission.Allowed("")
+	}
+
+	// allow subresources without decoding unless they are handled
+	if h.skipSubresources && req.SubResource != "" {
+		return admission.Allowed("")
+	}
+
+	resp := h.handle(ctx, req)
+
+	if h.recorder != nil {
+		typ := Mutating
+		if h.validator != nil {
+			typ = Validating
+		}
+		if err := h.recorder.Record(h.name, typ, req, resp); err != nil {
+			log.FromContext(ctx).Error(err, "failed to record admission request", "webhook", h.name)
+		}
+	}
+
+	if h.events != nil {
+		h.events.emit(ctx, h.name, req, resp)
+	}
+
+	return resp
+}
+
+func (h *handler) handle(ctx context.Context, req admission.Request) admission.Response {
 	// add metadata to context's logger
 	logger := log.FromContext(ctx).
 		WithValues("name", req.Name).
@@ -43,23 +110,34 @@ func (h *handler) Handle(ctx context.Context, req admission.Request) admission.R
 		WithValues("uid", req.UID)
 	ctx = log.IntoContext(ctx, logger)
 
-	// decode object
-	if len(req.Object.Raw) > 0 && req.Object.Object == nil {
-		obj := h.Object.DeepCopyObject()
-		if err := h.decoder.DecodeRaw(req.Object, obj); err != nil {
+	// decode objects, the old object is decoded on first access if lazy decoding is enabled
+	objects := newLazyObjects(req, h.Object, h.decoder)
+	ctx = objects.intoContext(ctx)
+
+	// skip the validator on updates which only change ignored paths
+	if h.validator != nil && len(h.ignoredChanges) > 0 && req.Operation == admissionv1.Update {
+		diff, err := objects.updateDiff()
+		if err != nil {
 			return admission.Errored(http.StatusBadRequest, err)
 		}
+		if diff != nil && diff.OnlyChanged(h.ignoredChanges...) {
+			return admission.Allowed("")
+		}
+	}
 
+	if !h.lazyDecoding || h.mutator != nil {
+		obj, err := objects.New()
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
 		req.Object.Object = obj
 	}
 
-	// decode old object
-	if len(req.OldObject.Raw) > 0 && req.OldObject.Object == nil {
-		obj := h.Object.DeepCopyObject()
-		if err := h.decoder.DecodeRaw(req.OldObject, obj); err != nil {
+	if !h.lazyDecoding {
+		obj, err := objects.Old()
+		if err != nil {
 			return admission.Errored(http.StatusBadRequest, err)
 		}
-
 		req.OldObject.Object = obj
 	}
 
@@ -67,11 +145,11 @@ func (h *handler) Handle(ctx context.Context, req admission.Request) admission.R
 	if h.validator != nil {
 		switch req.Operation {
 		case admissionv1.Create:
-			return h.validator.ValidateCreate(ctx, req, req.Object.Object)
+			return h.enforce(ctx, req, h.validator.ValidateCreate(ctx, req, req.Object.Object))
 		case admissionv1.Update:
-			return h.validator.ValidateUpdate(ctx, req, req.Object.Object, req.OldObject.Object)
+			return h.enforce(ctx, req, h.validator.ValidateUpdate(ctx, req, req.Object.Object, req.OldObject.Object))
 		case admissionv1.Delete:
-			return h.validator.ValidateDelete(ctx, req, req.OldObject.Object)
+			return h.enforce(ctx, req, h.validator.ValidateDelete(ctx, req, req.OldObject.Object))
 		}
 	}
 
@@ -86,7 +164,21 @@ func (h *handler) Handle(ctx context.Context, req admission.Request) admission.R
 					return admission.Errored(http.StatusInternalServerError, err)
 				}
 
-				return admission.PatchResponseFromRaw(req.Object.Raw, marshalled)
+				original, err := h.original(req)
+				if err != nil {
+					return admission.Errored(http.StatusInternalServerError, err)
+				}
+
+				patched := admission.PatchResponseFromRaw(original, marshalled)
+				patched.Warnings = resp.Warnings
+				resp = patched
+			}
+
+			if resp.Allowed && h.provenanceAnnotation != "" {
+				var err error
+				if resp, err = h.withPatchProvenance(req, resp); err != nil {
+					return admission.Errored(http.StatusInternalServerError, err)
+				}
 			}
 
 			return resp
@@ -97,3 +189,18 @@ func (h *handler) Handle(ctx context.Context, req admission.Request) admission.R
 
 	return admission.Denied("")
 }
+
+// original returns the raw object of the request which the patches are generated from, i.e. only the metadata of
+// the object if the webhook handles metadata.
+func (h *handler) original(req admission.Request) ([]byte, error) {
+	if _, ok := h.Object.(*metav1.PartialObjectMetadata); !ok {
+		return req.Object.Raw, nil
+	}
+
+	metadata, err := partialObjectMetadataOfKind(runtime.RawExtension{Raw: req.Object.Raw}, schema.GroupVersionKind(req.Kind))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(metadata)
+}
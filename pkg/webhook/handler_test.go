@@ -95,6 +95,117 @@ var _ = Describe("Handler", func() {
 			Ω(result.Allowed).Should(BeTrue())
 			Ω(result.Patches).Should(BeEmpty())
 		})
+		It("should mutate with a chain and generate combined patches", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			}
+			raw, err := json.Marshal(pod)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			h := withMutationHandler(MutatorChain(
+				&MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+						obj.(*corev1.Pod).Name = "bar"
+						return admission.Allowed("").WithWarnings("renamed")
+					},
+				},
+				&MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+						return admission.Patched("", jsonpatch.NewOperation("add", "/spec/nodeName", "jin"))
+					},
+				},
+			), &corev1.Pod{}, decoder)
+			result := h.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Object: runtime.RawExtension{
+						Raw: raw,
+					},
+					Operation: admissionv1.Create,
+				},
+			})
+			Ω(result.Allowed).Should(BeTrue())
+			Ω(result.Warnings).Should(ConsistOf("renamed"))
+			Ω(result.Patches).Should(ConsistOf(
+				jsonpatch.NewOperation("replace", "/metadata/name", "bar"),
+				jsonpatch.NewOperation("add", "/spec/nodeName", "jin"),
+			))
+		})
+		It("should record patch provenance", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						"provenance": `[{"webhook":"/other","kind":"/v1, Kind=Pod","fields":["metadata"],"time":null}]`,
+					},
+				},
+			}
+			raw, err := json.Marshal(pod)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			h := withMutationHandler(&MutateFunc{
+				Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+					obj.(*corev1.Pod).Spec.NodeName = "jin"
+					return admission.Allowed("")
+				},
+			}, &corev1.Pod{}, decoder)
+			h.name = "/mutate--v1-pod"
+			h.provenanceAnnotation = "provenance"
+
+			result := h.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Object: runtime.RawExtension{
+						Raw: raw,
+					},
+					Operation: admissionv1.Create,
+				},
+			})
+			Ω(result.Allowed).Should(BeTrue())
+			Ω(result.Patches).Should(HaveLen(2))
+
+			var value string
+			for _, patch := range result.Patches {
+				if patch.Path == "/metadata/annotations/provenance" {
+					value = patch.Value.(string)
+				}
+			}
+			records, err := ParsePatchProvenance(value)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(records).Should(HaveLen(2))
+			Ω(records[0].Webhook).Should(Equal("/other"))
+			Ω(records[1].Webhook).Should(Equal("/mutate--v1-pod"))
+			Ω(records[1].Kind).Should(Equal("/v1, Kind=Pod"))
+			Ω(records[1].Fields).Should(ConsistOf("spec"))
+			Ω(records[1].Time.IsZero()).Should(BeFalse())
+		})
+		It("should not record patch provenance without changes", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			}
+			raw, err := json.Marshal(pod)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			h := withMutationHandler(&MutateFunc{}, &corev1.Pod{}, decoder)
+			h.provenanceAnnotation = "provenance"
+
+			result := h.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Object: runtime.RawExtension{
+						Raw: raw,
+					},
+					Operation: admissionv1.Create,
+				},
+			})
+			Ω(result.Allowed).Should(BeTrue())
+			Ω(result.Patches).Should(BeEmpty())
+		})
 		It("should validate", func() {
 			pod := &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
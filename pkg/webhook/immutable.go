@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// immutableTag is the struct tag which marks fields as immutable, i.e. `webhook:"immutable"`.
+	immutableTag = "webhook"
+	// immutableTagValue is the value of the struct tag which marks fields as immutable.
+	immutableTagValue = "immutable"
+)
+
+// ensure ImmutableFieldsValidator implements Validator and LazyDecoder
+var (
+	_ Validator   = &ImmutableFieldsValidator{}
+	_ LazyDecoder = &ImmutableFieldsValidator{}
+)
+
+// immutablePaths caches the paths of the immutable fields by the type of the objects.
+var immutablePaths sync.Map
+
+// ImmutableFieldsValidator is a Validator which denies updates of immutable fields.
+type ImmutableFieldsValidator struct {
+	// Paths are the paths of the immutable fields, e.g. 'spec.selector'.
+	Paths []string
+}
+
+// ImmutableFields returns a Validator which denies updates of the fields of the paths, e.g. 'spec.selector', and of
+// the fields of the object type which are tagged with `webhook:"immutable"`. Setting or unsetting a field is an
+// update as well. Lists and maps are compared as a whole, the paths must not point into them.
+func ImmutableFields(paths ...string) *ImmutableFieldsValidator {
+	return &ImmutableFieldsValidator{Paths: paths}
+}
+
+// DecodesLazily implements the LazyDecoder interface.
+func (v *ImmutableFieldsValidator) DecodesLazily() {}
+
+// ValidateCreate implements the Validator interface.
+func (v *ImmutableFieldsValidator) ValidateCreate(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+	return admission.Allowed("")
+}
+
+// ValidateUpdate implements the Validator interface.
+func (v *ImmutableFieldsValidator) ValidateUpdate(ctx context.Context, req admission.Request, obj runtime.Object, oldObj runtime.Object) admission.Response {
+	var err error
+	if obj == nil {
+		if obj, err = Objects(ctx).New(); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if oldObj == nil {
+		if oldObj, err = Objects(ctx).Old(); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if obj == nil || oldObj == nil {
+		return admission.Allowed("")
+	}
+
+	newValues, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	oldValues, err := runtime.DefaultUnstructuredConverter.ToUnstructured(oldObj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	var errs field.ErrorList
+	for _, path := range v.paths(obj) {
+		fields := strings.Split(path, ".")
+		newValue, newFound, err := unstructured.NestedFieldNoCopy(newValues, fields...)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		oldValue, oldFound, err := unstructured.NestedFieldNoCopy(oldValues, fields...)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+
+		if newFound != oldFound || !equality.Semantic.DeepEqual(newValue, oldValue) {
+			errs = append(errs, field.Forbidden(field.NewPath(fields[0], fields[1:]...), "field is immutable"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return admission.Allowed("")
+	}
+
+	status := apierrors.NewInvalid(schema.GroupKind{Group: req.Kind.Group, Kind: req.Kind.Kind}, req.Name, errs).ErrStatus
+	resp := admission.Denied(status.Message)
+	resp.Result = &status
+	return resp
+}
+
+// ValidateDelete implements the Validator interface.
+func (v *ImmutableFieldsValidator) ValidateDelete(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+	return admission.Allowed("")
+}
+
+// paths returns the paths of the validator and of the tagged fields of the object type without duplicates.
+func (v *ImmutableFieldsValidator) paths(obj runtime.Object) []string {
+	paths := append([]string{}, v.Paths...)
+	for _, path := range taggedImmutablePaths(reflect.TypeOf(obj)) {
+		if !contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// taggedImmutablePaths returns the paths of the fields of the type which are tagged as immutable.
+func taggedImmutablePaths(typ reflect.Type) []string {
+	if paths, ok := immutablePaths.Load(typ); ok {
+		return paths.([]string)
+	}
+
+	paths := collectImmutablePaths(typ, "", map[reflect.Type]bool{})
+	immutablePaths.Store(typ, paths)
+	return paths
+}
+
+// collectImmutablePaths walks the fields of the struct type by their JSON names, lists and maps are not walked.
+func collectImmutablePaths(typ reflect.Type, prefix string, visited map[reflect.Type]bool) []string {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct || visited[typ] {
+		return nil
+	}
+	visited[typ] = true
+	defer delete(visited, typ)
+
+	var paths []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, inline := jsonFieldName(f)
+		if name == "-" {
+			continue
+		}
+
+		path := prefix
+		if !inline {
+			path = strings.TrimPrefix(fmt.Sprintf("%s.%s", prefix, name), ".")
+		}
+
+		if tag, ok := f.Tag.Lookup(immutableTag); ok && !inline && contains(strings.Split(tag, ","), immutableTagValue) {
+			paths = append(paths, path)
+			continue
+		}
+
+		paths = append(paths, collectImmutablePaths(f.Type, path, visited)...)
+	}
+
+	return paths
+}
+
+// jsonFieldName returns the JSON name of the field and true if the field is inlined.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := strings.Split(f.Tag.Get("json"), ",")
+	if contains(tag[1:], "inline") || (f.Anonymous && tag[0] == "") {
+		return "", true
+	}
+	if tag[0] == "" {
+		return f.Name, false
+	}
+
+	return tag[0], false
+}
@@ -0,0 +1,106 @@
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+type volume struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec volumeSpec `json:"spec,omitempty"`
+}
+
+type volumeSpec struct {
+	StorageClassName string `json:"storageClassName,omitempty" webhook:"immutable"`
+	Size             string `json:"size,omitempty"`
+}
+
+func (v *volume) DeepCopyObject() runtime.Object {
+	c := *v
+	v.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	return &c
+}
+
+var _ = Describe("ImmutableFields", func() {
+	var (
+		deployment *appsv1.Deployment
+	)
+	BeforeEach(func() {
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}},
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "bar"}}},
+			},
+		}
+	})
+	It("should deny updates of immutable fields", func() {
+		updated := deployment.DeepCopy()
+		updated.Spec.Selector.MatchLabels["app"] = "foo"
+		updated.Spec.Template.Labels["app"] = "foo"
+
+		resp, err := webhooktest.Update(updated, deployment).Validate(webhook.ImmutableFields("spec.selector", "spec.minReadySeconds"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDeniedWithReason(string(metav1.StatusReasonInvalid)))
+		Ω(resp.Result.Code).Should(BeEquivalentTo(http.StatusUnprocessableEntity))
+		Ω(resp.Result.Details.Causes).Should(Equal([]metav1.StatusCause{{
+			Type:    metav1.CauseType(field.ErrorTypeForbidden),
+			Message: "Forbidden: field is immutable",
+			Field:   "spec.selector",
+		}}))
+	})
+	It("should deny setting and unsetting immutable fields", func() {
+		updated := deployment.DeepCopy()
+		updated.Spec.Selector = nil
+
+		resp, err := webhooktest.Update(updated, deployment).Validate(webhook.ImmutableFields("spec.selector"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+
+		resp, err = webhooktest.Update(deployment, updated).Validate(webhook.ImmutableFields("spec.selector"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+	})
+	It("should allow updates of mutable fields", func() {
+		updated := deployment.DeepCopy()
+		updated.Spec.Template.Labels["version"] = "v2"
+
+		resp, err := webhooktest.Update(updated, deployment).Validate(webhook.ImmutableFields("spec.selector"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should deny updates of tagged fields", func() {
+		old := &volume{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec:       volumeSpec{StorageClassName: "standard", Size: "1Gi"},
+		}
+		updated := old.DeepCopyObject().(*volume)
+		updated.Spec.Size = "2Gi"
+
+		req := admission.Request{}
+		req.Kind = metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Volume"}
+		resp := webhook.ImmutableFields().ValidateUpdate(context.TODO(), req, updated, old)
+		Ω(resp).Should(webhooktest.BeAllowed())
+
+		updated.Spec.StorageClassName = "premium"
+		resp = webhook.ImmutableFields().ValidateUpdate(context.TODO(), req, updated, old)
+		Ω(resp).Should(webhooktest.BeDenied())
+		Ω(resp.Result.Details.Causes).Should(HaveLen(1))
+		Ω(resp.Result.Details.Causes[0].Field).Should(Equal("spec.storageClassName"))
+	})
+})
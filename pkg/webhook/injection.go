@@ -1,6 +1,12 @@
 package webhook
 
 import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -42,3 +48,122 @@ func (i *InjectedDecoder) InjectDecoder(decoder admission.Decoder) error {
 	i.Decoder = decoder
 	return nil
 }
+
+// EventRecorderInjector is used to inject a record.EventRecorder into webhook handlers.
+type EventRecorderInjector interface {
+	InjectEventRecorder(record.EventRecorder) error
+}
+
+// ensure InjectedEventRecorder implements EventRecorderInjector
+var _ EventRecorderInjector = &InjectedEventRecorder{}
+
+// InjectedEventRecorder holds an injected record.EventRecorder
+type InjectedEventRecorder struct {
+	EventRecorder record.EventRecorder
+}
+
+// InjectEventRecorder implements the EventRecorderInjector interface.
+func (i *InjectedEventRecorder) InjectEventRecorder(recorder record.EventRecorder) error {
+	i.EventRecorder = recorder
+	return nil
+}
+
+// APIReaderInjector is used to inject an uncached client.Reader into webhook handlers.
+type APIReaderInjector interface {
+	InjectAPIReader(client.Reader) error
+}
+
+// ensure InjectedAPIReader implements APIReaderInjector
+var _ APIReaderInjector = &InjectedAPIReader{}
+
+// InjectedAPIReader holds an injected uncached client.Reader
+type InjectedAPIReader struct {
+	APIReader client.Reader
+}
+
+// InjectAPIReader implements the APIReaderInjector interface.
+func (i *InjectedAPIReader) InjectAPIReader(reader client.Reader) error {
+	i.APIReader = reader
+	return nil
+}
+
+// SchemeInjector is used to inject a runtime.Scheme into webhook handlers.
+type SchemeInjector interface {
+	InjectScheme(*runtime.Scheme) error
+}
+
+// ensure InjectedScheme implements SchemeInjector
+var _ SchemeInjector = &InjectedScheme{}
+
+// InjectedScheme holds an injected runtime.Scheme
+type InjectedScheme struct {
+	Scheme *runtime.Scheme
+}
+
+// InjectScheme implements the SchemeInjector interface.
+func (i *InjectedScheme) InjectScheme(scheme *runtime.Scheme) error {
+	i.Scheme = scheme
+	return nil
+}
+
+// RESTMapperInjector is used to inject a meta.RESTMapper into webhook handlers.
+type RESTMapperInjector interface {
+	InjectRESTMapper(meta.RESTMapper) error
+}
+
+// ensure InjectedRESTMapper implements RESTMapperInjector
+var _ RESTMapperInjector = &InjectedRESTMapper{}
+
+// InjectedRESTMapper holds an injected meta.RESTMapper
+type InjectedRESTMapper struct {
+	RESTMapper meta.RESTMapper
+}
+
+// InjectRESTMapper implements the RESTMapperInjector interface.
+func (i *InjectedRESTMapper) InjectRESTMapper(mapper meta.RESTMapper) error {
+	i.RESTMapper = mapper
+	return nil
+}
+
+// LoggerInjector is used to inject a logr.Logger into webhook handlers.
+type LoggerInjector interface {
+	InjectLogger(logr.Logger) error
+}
+
+// ensure InjectedLogger implements LoggerInjector
+var _ LoggerInjector = &InjectedLogger{}
+
+// InjectedLogger holds an injected logr.Logger
+type InjectedLogger struct {
+	Logger logr.Logger
+}
+
+// InjectLogger implements the LoggerInjector interface.
+func (i *InjectedLogger) InjectLogger(logger logr.Logger) error {
+	i.Logger = logger
+	return nil
+}
+
+// ConfigInjector is used to inject a ConfigProvider into webhook handlers.
+type ConfigInjector interface {
+	InjectConfig(ConfigProvider) error
+}
+
+// ensure InjectedConfig implements ConfigInjector
+var _ ConfigInjector = &InjectedConfig[struct{}]{}
+
+// InjectedConfig holds an injected ConfigSource
+type InjectedConfig[T any] struct {
+	Config *ConfigSource[T]
+}
+
+// InjectConfig implements the ConfigInjector interface.
+func (i *InjectedConfig[T]) InjectConfig(provider ConfigProvider) error {
+	source, ok := provider.(*ConfigSource[T])
+	if !ok {
+		return fmt.Errorf("unable to inject configuration provider %T, expected %T", provider, i.Config)
+	}
+
+	i.Config = source
+	return nil
+}
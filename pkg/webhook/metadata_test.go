@@ -0,0 +1,115 @@
+package webhook_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Metadata", func() {
+	var (
+		configMapKind  = corev1.SchemeGroupVersion.WithKind("ConfigMap")
+		deploymentKind = appsv1.SchemeGroupVersion.WithKind("Deployment")
+
+		cm *corev1.ConfigMap
+	)
+	BeforeEach(func() {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", Labels: map[string]string{"app": "bar"}},
+			Data:       map[string]string{"key": "value"},
+		}
+	})
+	It("should decode the metadata of the objects", func() {
+		validating, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForMetadata(configMapKind).
+			Build(&webhook.ValidateFuncs{
+				CreateFunc: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+					metadata, ok := obj.(*metav1.PartialObjectMetadata)
+					Ω(ok).Should(BeTrue())
+					Ω(metadata.Kind).Should(Equal("ConfigMap"))
+					if metadata.Labels["owner"] == "" {
+						return admission.Denied("label owner is required")
+					}
+					return admission.Allowed("")
+				},
+			})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Create(cm).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+
+		cm.Labels["owner"] = "foo"
+		resp, err = webhooktest.Create(cm).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should generate patches of the metadata only", func() {
+		_, mutating, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForMetadata(configMapKind).
+			Build(&webhook.MutateFunc{
+				Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+					metadata := obj.(*metav1.PartialObjectMetadata)
+					metadata.Labels["owner"] = "foo"
+					metadata.Annotations = map[string]string{"owned": "true"}
+					return admission.Allowed("")
+				},
+			})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Create(cm).Handle(mutating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+		Ω(resp.Patches).Should(HaveLen(2))
+		Ω(resp).Should(webhooktest.HavePatch("add", "/metadata/labels/owner", "foo"))
+		Ω(resp).Should(webhooktest.HavePatch("add", "/metadata/annotations", map[string]string{"owned": "true"}))
+
+		patched, err := webhooktest.ApplyPatch(cm, resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(patched.Labels).Should(Equal(map[string]string{"app": "bar", "owner": "foo"}))
+		Ω(patched.Data).Should(Equal(map[string]string{"key": "value"}))
+	})
+	It("should handle and return rules of all kinds", func() {
+		blder := webhook.NewGenericWebhook(scheme.Scheme).
+			ForMetadata(configMapKind, deploymentKind)
+		Ω(blder.Handles(configMapKind)).Should(BeTrue())
+		Ω(blder.Handles(deploymentKind)).Should(BeTrue())
+		Ω(blder.Handles(corev1.SchemeGroupVersion.WithKind("Pod"))).Should(BeFalse())
+
+		rules, err := blder.Rules()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rules).Should(HaveLen(2))
+		Ω(rules[0].Resources).Should(Equal([]string{"configmaps"}))
+		Ω(rules[1].APIGroups).Should(Equal([]string{"apps"}))
+		Ω(rules[1].Resources).Should(Equal([]string{"deployments"}))
+	})
+	It("should require the path of a webhook of multiple kinds", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForMetadata(configMapKind, deploymentKind).
+			Build(&webhook.ValidatingWebhook{})
+		Ω(err).Should(HaveOccurred())
+
+		_, _, err = webhook.NewGenericWebhook(scheme.Scheme).
+			ForMetadata(configMapKind, deploymentKind).
+			WithValidatePath("/validate-metadata").
+			Build(&webhook.ValidatingWebhook{})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+	It("should fail without kinds", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForMetadata().
+			Build(&webhook.ValidatingWebhook{})
+		Ω(err).Should(HaveOccurred())
+	})
+})
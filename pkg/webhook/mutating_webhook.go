@@ -20,6 +20,11 @@ var _ Mutator = &MutatingWebhook{}
 type MutatingWebhook struct {
 	InjectedClient
 	InjectedDecoder
+	InjectedEventRecorder
+	InjectedAPIReader
+	InjectedScheme
+	InjectedRESTMapper
+	InjectedLogger
 }
 
 // Mutate implements the Mutator interface.
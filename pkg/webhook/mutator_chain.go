@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// MutatorChain returns a Mutator which passes the decoded object through the given mutators in order.
+// The mutations of all stages are combined into a single patch by the handler. A stage which does not allow the
+// request short-circuits the chain and its response is returned.
+func MutatorChain(mutators ...Mutator) Mutator {
+	return &mutatorChain{mutators: mutators}
+}
+
+// mutatorChain is an ordered list of mutators, implements the Mutator interface.
+type mutatorChain struct {
+	mutators []Mutator
+}
+
+// Mutate implements the Mutator interface. The stages are applied to the raw object of the request, i.e. patches
+// are valid for the request even if fields are dropped or defaulted by decoding the object. The combined patch is
+// returned explicitly if the request contains the raw object, otherwise only the object is mutated.
+func (c *mutatorChain) Mutate(ctx context.Context, req admission.Request, obj runtime.Object) admission.Response {
+	logger := log.FromContext(ctx)
+
+	current := req.Object.Raw
+	if len(current) == 0 {
+		var err error
+		if current, err = json.Marshal(obj); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
+	var warnings []string
+	for stage, mutator := range c.mutators {
+		before := current
+		decoded, err := json.Marshal(obj)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+
+		resp := mutator.Mutate(ctx, req, obj)
+		warnings = append(warnings, resp.Warnings...)
+		if !resp.Allowed {
+			logger.Info("mutator denied request", "stage", stage, "mutator", fmt.Sprintf("%T", mutator))
+			resp.Warnings = warnings
+			return resp
+		}
+
+		// explicit patches take precedence over modifications of the object
+		patches := resp.Patches
+		if patches == nil {
+			mutated, err := json.Marshal(obj)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			if patches, err = jsonpatch.CreatePatch(decoded, mutated); err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+		}
+
+		if current, err = ApplyJSONPatch(current, patches); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if resp.Patches != nil {
+			if err := resetInto(current, obj); err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+		}
+
+		if logger.V(1).Enabled() {
+			changed, err := jsonpatch.CreatePatch(before, current)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			logger.V(1).Info("mutator applied", "stage", stage, "mutator", fmt.Sprintf("%T", mutator), "paths", patchPaths(changed))
+		}
+	}
+
+	resp := admission.Allowed("")
+	resp.Warnings = warnings
+	if len(req.Object.Raw) > 0 {
+		patches, err := jsonpatch.CreatePatch(req.Object.Raw, current)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		resp.Patches = append([]jsonpatch.JsonPatchOperation{}, patches...)
+	}
+	return resp
+}
@@ -0,0 +1,127 @@
+package webhook_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gomodules.xyz/jsonpatch/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+var _ = Describe("Mutator Chain", func() {
+	Context("MutatorChain", func() {
+		var (
+			pod *corev1.Pod
+		)
+		BeforeEach(func() {
+			pod = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			}
+		})
+		It("should allow if empty", func() {
+			result := webhook.MutatorChain().Mutate(context.TODO(), admission.Request{}, pod)
+			Ω(result.Allowed).Should(BeTrue())
+		})
+		It("should pass the object through all mutators in order", func() {
+			result := webhook.MutatorChain(
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+						obj.(*corev1.Pod).Spec.NodeName = "jin"
+						return admission.Allowed("")
+					},
+				},
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+						Ω(obj.(*corev1.Pod).Spec.NodeName).Should(Equal("jin"))
+						obj.(*corev1.Pod).Spec.NodeName += "-yang"
+						return admission.Allowed("")
+					},
+				},
+			).Mutate(context.TODO(), admission.Request{}, pod)
+			Ω(result.Allowed).Should(BeTrue())
+			Ω(result.Patches).Should(BeNil())
+			Ω(pod.Spec.NodeName).Should(Equal("jin-yang"))
+		})
+		It("should apply explicit patches of a stage", func() {
+			result := webhook.MutatorChain(
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+						obj.(*corev1.Pod).Spec.NodeName = "ignored"
+						return admission.Patched("", jsonpatch.NewOperation("replace", "/metadata/name", "baz"))
+					},
+				},
+			).Mutate(context.TODO(), admission.Request{}, pod)
+			Ω(result.Allowed).Should(BeTrue())
+			Ω(pod.Name).Should(Equal("baz"))
+			Ω(pod.Spec.NodeName).Should(BeEmpty())
+		})
+		It("should fail if explicit patches cannot be applied", func() {
+			result := webhook.MutatorChain(
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+						return admission.Patched("", jsonpatch.NewOperation("remove", "/spec/foo/bar", nil))
+					},
+				},
+			).Mutate(context.TODO(), admission.Request{}, pod)
+			Ω(result.Allowed).Should(BeFalse())
+		})
+		It("should apply the stages to the raw object of the request", func() {
+			req := admission.Request{}
+			req.Object.Raw = []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"foo"},"spec":{"unknown":"foo"}}`)
+
+			result := webhook.MutatorChain(
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+						return admission.Patched("", jsonpatch.NewOperation("replace", "/spec/unknown", "bar"))
+					},
+				},
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+						obj.(*corev1.Pod).Spec.NodeName = "baz"
+						return admission.Allowed("")
+					},
+				},
+			).Mutate(context.TODO(), req, pod)
+			Ω(result.Allowed).Should(BeTrue())
+			Ω(result.Patches).Should(ConsistOf(
+				jsonpatch.NewOperation("replace", "/spec/unknown", "bar"),
+				jsonpatch.NewOperation("add", "/spec/nodeName", "baz"),
+			))
+		})
+		It("should short-circuit on denial and collect warnings", func() {
+			called := false
+			result := webhook.MutatorChain(
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+						return admission.Allowed("").WithWarnings("first")
+					},
+				},
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+						return admission.Denied("second")
+					},
+				},
+				&webhook.MutateFunc{
+					Func: func(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+						called = true
+						return admission.Allowed("")
+					},
+				},
+			).Mutate(context.TODO(), admission.Request{}, pod)
+			Ω(result.Allowed).Should(BeFalse())
+			Ω(result.Result.Message).Should(Equal("second"))
+			Ω(result.Warnings).Should(ConsistOf("first"))
+			Ω(called).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// objectsKey is the context key of the LazyObjects of a request.
+type objectsKey struct{}
+
+// LazyDecoder is implemented by webhooks which read the objects of a request from Objects(ctx), only such webhooks
+// support lazy decoding since the validator is invoked with nil objects, see Builder.WithLazyDecoding.
+type LazyDecoder interface {
+	// DecodesLazily marks the webhook as reading the objects from Objects(ctx).
+	DecodesLazily()
+}
+
+// LazyDecoding is embedded by webhooks which read the objects of a request from Objects(ctx) in order to implement
+// the LazyDecoder interface.
+type LazyDecoding struct{}
+
+// DecodesLazily implements the LazyDecoder interface.
+func (LazyDecoding) DecodesLazily() {}
+
+// LazyObjects provides the objects of an admission request which are decoded on first access. Webhooks which only
+// inspect the metadata of the objects use Metadata and OldMetadata which parse the metadata only.
+type LazyObjects struct {
+	kind    schema.GroupVersionKind
+	object  runtime.Object
+	decoder admission.Decoder
+
+	newObject lazyObject
+	oldObject lazyObject
+
+	diffOnce sync.Once
+	diff     *Diff
+	diffErr  error
+}
+
+// lazyObject is an object of a request which is decoded once.
+type lazyObject struct {
+	raw runtime.RawExtension
+
+	objectOnce sync.Once
+	object     runtime.Object
+	objectErr  error
+
+	metadataOnce sync.Once
+	metadata     *metav1.PartialObjectMetadata
+	metadataErr  error
+}
+
+// Objects returns the LazyObjects of the request which is handled with the context. The objects are nil if the
+// context doesn't belong to a request of a generic webhook.
+func Objects(ctx context.Context) *LazyObjects {
+	if objects, ok := ctx.Value(objectsKey{}).(*LazyObjects); ok {
+		return objects
+	}
+
+	return &LazyObjects{}
+}
+
+// newLazyObjects returns the LazyObjects of the request which are decoded into copies of the object.
+func newLazyObjects(req admission.Request, object runtime.Object, decoder admission.Decoder) *LazyObjects {
+	return &LazyObjects{
+		kind:      schema.GroupVersionKind(req.Kind),
+		object:    object,
+		decoder:   decoder,
+		newObject: lazyObject{raw: req.Object},
+		oldObject: lazyObject{raw: req.OldObject},
+	}
+}
+
+// intoContext returns a copy of the context which holds the LazyObjects.
+func (o *LazyObjects) intoContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, objectsKey{}, o)
+}
+
+// New returns the decoded object of the request, nil on DELETE.
+func (o *LazyObjects) New() (runtime.Object, error) {
+	return o.newObject.decode(o.kind, o.object, o.decoder)
+}
+
+// Old returns the decoded old object of the request, nil on CREATE.
+func (o *LazyObjects) Old() (runtime.Object, error) {
+	return o.oldObject.decode(o.kind, o.object, o.decoder)
+}
+
+// Metadata returns the metadata of the object of the request or of the old object on DELETE.
+func (o *LazyObjects) Metadata() (*metav1.PartialObjectMetadata, error) {
+	if o.newObject.empty() {
+		return o.OldMetadata()
+	}
+
+	return o.newObject.parseMetadata()
+}
+
+// OldMetadata returns the metadata of the old object of the request, nil on CREATE.
+func (o *LazyObjects) OldMetadata() (*metav1.PartialObjectMetadata, error) {
+	return o.oldObject.parseMetadata()
+}
+
+// empty returns true if the request doesn't contain the object.
+func (l *lazyObject) empty() bool {
+	return len(l.raw.Raw) == 0 && l.raw.Object == nil
+}
+
+// decode decodes the object into a copy of the given object once.
+func (l *lazyObject) decode(kind schema.GroupVersionKind, object runtime.Object, decoder admission.Decoder) (runtime.Object, error) {
+	l.objectOnce.Do(func() {
+		if l.raw.Object != nil || len(l.raw.Raw) == 0 {
+			l.object = l.raw.Object
+			return
+		}
+
+		// the metadata of objects of any kind is parsed without the decoder
+		if _, ok := object.(*metav1.PartialObjectMetadata); ok {
+			var metadata *metav1.PartialObjectMetadata
+			if metadata, l.objectErr = partialObjectMetadataOfKind(l.raw, kind); l.objectErr == nil {
+				l.object = metadata
+			}
+			return
+		}
+
+		obj := object.DeepCopyObject()
+		if l.objectErr = decoder.DecodeRaw(l.raw, obj); l.objectErr == nil {
+			l.object = obj
+		}
+	})
+
+	return l.object, l.objectErr
+}
+
+// parseMetadata parses the metadata of the object once.
+func (l *lazyObject) parseMetadata() (*metav1.PartialObjectMetadata, error) {
+	l.metadataOnce.Do(func() {
+		l.metadata, l.metadataErr = partialObjectMetadata(l.raw)
+	})
+
+	return l.metadata, l.metadataErr
+}
+
+// partialObjectMetadataOfKind returns the metadata of the object, the kind is set if the raw object lacks its type.
+func partialObjectMetadataOfKind(raw runtime.RawExtension, kind schema.GroupVersionKind) (*metav1.PartialObjectMetadata, error) {
+	metadata, err := partialObjectMetadata(raw)
+	if err != nil || metadata == nil {
+		return metadata, err
+	}
+
+	if metadata.GroupVersionKind().Empty() {
+		metadata.SetGroupVersionKind(kind)
+	}
+	return metadata, nil
+}
+
+// partialObjectMetadata returns the metadata of the object, the raw object is parsed if it isn't decoded yet.
+func partialObjectMetadata(raw runtime.RawExtension) (*metav1.PartialObjectMetadata, error) {
+	if raw.Object != nil {
+		accessor, err := meta.Accessor(raw.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata := &metav1.PartialObjectMetadata{}
+		metadata.SetGroupVersionKind(raw.Object.GetObjectKind().GroupVersionKind())
+		metadata.ObjectMeta = metav1.ObjectMeta{
+			Name:                       accessor.GetName(),
+			GenerateName:               accessor.GetGenerateName(),
+			Namespace:                  accessor.GetNamespace(),
+			UID:                        accessor.GetUID(),
+			ResourceVersion:            accessor.GetResourceVersion(),
+			Generation:                 accessor.GetGeneration(),
+			CreationTimestamp:          accessor.GetCreationTimestamp(),
+			DeletionTimestamp:          accessor.GetDeletionTimestamp(),
+			DeletionGracePeriodSeconds: accessor.GetDeletionGracePeriodSeconds(),
+			Labels:                     accessor.GetLabels(),
+			Annotations:                accessor.GetAnnotations(),
+			OwnerReferences:            accessor.GetOwnerReferences(),
+			Finalizers:                 accessor.GetFinalizers(),
+			ManagedFields:              accessor.GetManagedFields(),
+		}
+		return metadata, nil
+	}
+
+	if len(raw.Raw) == 0 {
+		return nil, nil
+	}
+
+	metadata := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(raw.Raw, metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,214 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+// lazyValidateFuncs are ValidateFuncs which read the objects from webhook.Objects(ctx).
+type lazyValidateFuncs struct {
+	webhook.ValidateFuncs
+	webhook.LazyDecoding
+}
+
+// lazyMutateFunc is a MutateFunc which supports lazy decoding.
+type lazyMutateFunc struct {
+	webhook.MutateFunc
+	webhook.LazyDecoding
+}
+
+var _ = Describe("Objects", func() {
+	var (
+		cm    *corev1.ConfigMap
+		oldCm *corev1.ConfigMap
+	)
+	BeforeEach(func() {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", Labels: map[string]string{"app": "bar"}},
+			Data:       map[string]string{"key": "new"},
+		}
+		oldCm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", Labels: map[string]string{"app": "foo"}},
+			Data:       map[string]string{"key": "old"},
+		}
+	})
+	It("should provide the decoded objects", func() {
+		validator := &webhook.ValidateFuncs{
+			UpdateFunc: func(ctx context.Context, _ admission.Request, oldObj runtime.Object, obj runtime.Object) admission.Response {
+				newObj, err := webhook.Objects(ctx).New()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(newObj).Should(BeIdenticalTo(obj))
+
+				old, err := webhook.Objects(ctx).Old()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(old).Should(BeIdenticalTo(oldObj))
+				return admission.Allowed("")
+			},
+		}
+
+		resp, err := webhooktest.Update(cm, oldCm).Validate(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should decode the objects on first access if lazy decoding is enabled", func() {
+		validating, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.ConfigMap{}).
+			WithLazyDecoding().
+			Build(&lazyValidateFuncs{ValidateFuncs: webhook.ValidateFuncs{
+				UpdateFunc: func(ctx context.Context, _ admission.Request, oldObj runtime.Object, obj runtime.Object) admission.Response {
+					Ω(obj).Should(BeNil())
+					Ω(oldObj).Should(BeNil())
+
+					metadata, err := webhook.Objects(ctx).Metadata()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(metadata.Labels).Should(Equal(map[string]string{"app": "bar"}))
+					oldMetadata, err := webhook.Objects(ctx).OldMetadata()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(oldMetadata.Labels).Should(Equal(map[string]string{"app": "foo"}))
+
+					newObj, err := webhook.Objects(ctx).New()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(newObj.(*corev1.ConfigMap).Data).Should(Equal(map[string]string{"key": "new"}))
+					old, err := webhook.Objects(ctx).Old()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(old.(*corev1.ConfigMap).Data).Should(Equal(map[string]string{"key": "old"}))
+					return admission.Allowed("")
+				},
+			}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Update(cm, oldCm).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should provide the metadata of the old object on delete", func() {
+		validating, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.ConfigMap{}).
+			WithLazyDecoding().
+			Build(&lazyValidateFuncs{ValidateFuncs: webhook.ValidateFuncs{
+				DeleteFunc: func(ctx context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+					newObj, err := webhook.Objects(ctx).New()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(newObj).Should(BeNil())
+
+					metadata, err := webhook.Objects(ctx).Metadata()
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(metadata.Name).Should(Equal("bar"))
+					return admission.Allowed("")
+				},
+			}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Delete(oldCm).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+	})
+	It("should decode the object of mutating webhooks if lazy decoding is enabled", func() {
+		_, mutating, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.ConfigMap{}).
+			WithLazyDecoding().
+			Build(&lazyMutateFunc{MutateFunc: webhook.MutateFunc{
+				Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+					obj.(*corev1.ConfigMap).Data["key"] = "mutated"
+					return admission.Allowed("")
+				},
+			}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := webhooktest.Update(cm, oldCm).Handle(mutating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.HavePatch("replace", "/data/key", "mutated"))
+	})
+	It("should fail if the webhook doesn't support lazy decoding", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.ConfigMap{}).
+			WithLazyDecoding().
+			Build(&webhook.ValidateFuncs{})
+		Ω(err).Should(HaveOccurred())
+	})
+	It("should return nil objects outside of a request", func() {
+		obj, err := webhook.Objects(context.TODO()).New()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(obj).Should(BeNil())
+
+		metadata, err := webhook.Objects(context.TODO()).Metadata()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(metadata).Should(BeNil())
+	})
+})
+
+// benchmarkMetadataValidation benchmarks a validator which only inspects the labels of a large ConfigMap.
+func benchmarkMetadataValidation(b *testing.B, lazy bool) {
+	data := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		data[fmt.Sprintf("key-%d", i)] = strings.Repeat("x", 1024)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", Labels: map[string]string{"app": "bar"}},
+		Data:       data,
+	}
+
+	validator := &lazyValidateFuncs{ValidateFuncs: webhook.ValidateFuncs{
+		UpdateFunc: func(ctx context.Context, _ admission.Request, _ runtime.Object, obj runtime.Object) admission.Response {
+			var labels map[string]string
+			if lazy {
+				metadata, err := webhook.Objects(ctx).Metadata()
+				if err != nil {
+					return admission.Errored(http.StatusInternalServerError, err)
+				}
+				labels = metadata.Labels
+			} else {
+				labels = obj.(*corev1.ConfigMap).Labels
+			}
+
+			if labels["app"] == "" {
+				return admission.Denied("label app is required")
+			}
+			return admission.Allowed("")
+		},
+	}}
+
+	blder := webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.ConfigMap{})
+	if lazy {
+		blder = blder.WithLazyDecoding()
+	}
+	validating, _, err := blder.Build(validator)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req, err := webhooktest.Update(cm, cm.DeepCopy()).Build()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if resp := validating.Handle(context.TODO(), req); !resp.Allowed {
+			b.Fatal(resp.Result.Message)
+		}
+	}
+}
+
+func BenchmarkEagerDecoding(b *testing.B) {
+	benchmarkMetadataValidation(b, false)
+}
+
+func BenchmarkLazyDecoding(b *testing.B) {
+	benchmarkMetadataValidation(b, true)
+}
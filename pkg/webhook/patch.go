@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	jsonpatchapply "github.com/evanphx/json-patch/v5"
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ApplyJSONPatch applies the JSON patch operations to the raw object and returns the patched document.
+func ApplyJSONPatch(raw []byte, patches []jsonpatch.JsonPatchOperation) ([]byte, error) {
+	marshalled, err := json.Marshal(patches)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := jsonpatchapply.DecodePatch(marshalled)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := patch.Apply(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	return patched, nil
+}
+
+// resetInto replaces the content of the object with the given JSON document.
+func resetInto(raw []byte, obj runtime.Object) error {
+	value := reflect.ValueOf(obj).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	return json.Unmarshal(raw, obj)
+}
+
+// patchPaths returns the paths of the JSON patch operations.
+func patchPaths(patches []jsonpatch.JsonPatchOperation) []string {
+	paths := make([]string, 0, len(patches))
+	for _, patch := range patches {
+		paths = append(paths, patch.Path)
+	}
+
+	return paths
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ensure DeletionProtection and FinalizerGuard implement Validator and LazyDecoder
+var (
+	_ Validator   = &DeletionProtection{}
+	_ LazyDecoder = &DeletionProtection{}
+	_ Validator   = &FinalizerGuard{}
+	_ LazyDecoder = &FinalizerGuard{}
+)
+
+// DeletionProtection is a Validator which denies the deletion of protected objects. An object is protected if the
+// annotation or the label is set to 'true'. The protection can only be removed by an update of the users or groups
+// which are allowed to override it, these users and groups are also allowed to delete protected objects.
+type DeletionProtection struct {
+	// Annotation protects the objects on which it is set to 'true'.
+	Annotation string
+	// Label protects the objects on which it is set to 'true'.
+	Label string
+	// Users are the names of the users which are allowed to override the protection.
+	Users []string
+	// Groups are the groups of the users which are allowed to override the protection.
+	Groups []string
+}
+
+// DecodesLazily implements the LazyDecoder interface.
+func (p *DeletionProtection) DecodesLazily() {}
+
+// ValidateCreate implements the Validator interface.
+func (p *DeletionProtection) ValidateCreate(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+	return admission.Allowed("")
+}
+
+// ValidateUpdate implements the Validator interface, the protection must not be removed unless it is overridden.
+func (p *DeletionProtection) ValidateUpdate(ctx context.Context, req admission.Request, obj runtime.Object, oldObj runtime.Object) admission.Response {
+	newMetadata, err := objectMetadata(obj, Objects(ctx).Metadata)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	oldMetadata, err := objectMetadata(oldObj, Objects(ctx).OldMetadata)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !p.protects(oldMetadata) || p.protects(newMetadata) || p.overridden(req) {
+		return admission.Allowed("")
+	}
+
+	return deniedWithReason(metav1.StatusReasonForbidden, fmt.Sprintf("%s %s is protected against deletion, the %s must not be removed",
+		req.Kind.Kind, namespacedName(oldMetadata), p.marker()))
+}
+
+// ValidateDelete implements the Validator interface, protected objects must not be deleted unless the protection is overridden.
+func (p *DeletionProtection) ValidateDelete(ctx context.Context, req admission.Request, obj runtime.Object) admission.Response {
+	metadata, err := objectMetadata(obj, Objects(ctx).OldMetadata)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !p.protects(metadata) || p.overridden(req) {
+		return admission.Allowed("")
+	}
+
+	return deniedWithReason(metav1.StatusReasonForbidden, fmt.Sprintf("%s %s is protected against deletion by the %s",
+		req.Kind.Kind, namespacedName(metadata), p.marker()))
+}
+
+// protects returns true if the annotation or the label of the object is set to true.
+func (p *DeletionProtection) protects(metadata metav1.Object) bool {
+	if metadata == nil {
+		return false
+	}
+
+	return isTrue(metadata.GetAnnotations(), p.Annotation) || isTrue(metadata.GetLabels(), p.Label)
+}
+
+// overridden returns true if the user of the request is allowed to override the protection.
+func (p *DeletionProtection) overridden(req admission.Request) bool {
+	for _, user := range p.Users {
+		if req.UserInfo.Username == user {
+			return true
+		}
+	}
+	for _, group := range p.Groups {
+		for _, g := range req.UserInfo.Groups {
+			if g == group {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// marker returns a description of the annotation and the label which protect the objects.
+func (p *DeletionProtection) marker() string {
+	var markers []string
+	if p.Annotation != "" {
+		markers = append(markers, fmt.Sprintf("annotation %s", p.Annotation))
+	}
+	if p.Label != "" {
+		markers = append(markers, fmt.Sprintf("label %s", p.Label))
+	}
+
+	return strings.Join(markers, " or ")
+}
+
+// FinalizerGuard is a Validator which denies updates removing the protected finalizers from objects which are not
+// terminating, i.e. the finalizers can only be removed once the deletion of the object was requested.
+type FinalizerGuard struct {
+	// Finalizers are the protected finalizers.
+	Finalizers []string
+}
+
+// DecodesLazily implements the LazyDecoder interface.
+func (g *FinalizerGuard) DecodesLazily() {}
+
+// ValidateCreate implements the Validator interface.
+func (g *FinalizerGuard) ValidateCreate(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+	return admission.Allowed("")
+}
+
+// ValidateUpdate implements the Validator interface.
+func (g *FinalizerGuard) ValidateUpdate(ctx context.Context, req admission.Request, obj runtime.Object, oldObj runtime.Object) admission.Response {
+	newMetadata, err := objectMetadata(obj, Objects(ctx).Metadata)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	oldMetadata, err := objectMetadata(oldObj, Objects(ctx).OldMetadata)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if newMetadata == nil || oldMetadata == nil || oldMetadata.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	var removed []string
+	for _, finalizer := range g.Finalizers {
+		if contains(oldMetadata.GetFinalizers(), finalizer) && !contains(newMetadata.GetFinalizers(), finalizer) {
+			removed = append(removed, finalizer)
+		}
+	}
+	if len(removed) == 0 {
+		return admission.Allowed("")
+	}
+
+	return deniedWithReason(metav1.StatusReasonForbidden, fmt.Sprintf("finalizers %s must not be removed from %s %s which is not terminating",
+		strings.Join(removed, ", "), req.Kind.Kind, namespacedName(oldMetadata)))
+}
+
+// ValidateDelete implements the Validator interface.
+func (g *FinalizerGuard) ValidateDelete(_ context.Context, _ admission.Request, _ runtime.Object) admission.Response {
+	return admission.Allowed("")
+}
+
+// objectMetadata returns the metadata of the object, the metadata is parsed lazily if the object isn't decoded.
+func objectMetadata(obj runtime.Object, lazy func() (*metav1.PartialObjectMetadata, error)) (metav1.Object, error) {
+	if obj != nil {
+		return meta.Accessor(obj)
+	}
+
+	metadata, err := lazy()
+	if err != nil || metadata == nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// namespacedName returns the namespace and the name of the object.
+func namespacedName(metadata metav1.Object) string {
+	if metadata.GetNamespace() == "" {
+		return metadata.GetName()
+	}
+
+	return metadata.GetNamespace() + "/" + metadata.GetName()
+}
+
+// isTrue returns true if the value of the key is set to true.
+func isTrue(values map[string]string, key string) bool {
+	if key == "" {
+		return false
+	}
+
+	value, err := strconv.ParseBool(values[key])
+	return err == nil && value
+}
+
+// contains returns true if the values contain the value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,130 @@
+package webhook_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Protection", func() {
+	Context("DeletionProtection", func() {
+		var (
+			protection *webhook.DeletionProtection
+			ns         *corev1.Namespace
+		)
+		BeforeEach(func() {
+			protection = &webhook.DeletionProtection{
+				Annotation: "example.com/protected",
+				Label:      "example.com/protected",
+				Users:      []string{"admin"},
+				Groups:     []string{"system:masters"},
+			}
+			ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{"example.com/protected": "true"},
+			}}
+		})
+		It("should deny the deletion of protected objects", func() {
+			resp, err := webhooktest.Delete(ns).Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDeniedWithReason("Forbidden"))
+			Ω(resp.Result.Message).Should(ContainSubstring("Namespace foo is protected against deletion"))
+
+			ns.Annotations = nil
+			ns.Labels = map[string]string{"example.com/protected": "true"}
+			resp, err = webhooktest.Delete(ns).Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDenied())
+		})
+		It("should allow the deletion of unprotected objects", func() {
+			ns.Annotations["example.com/protected"] = "false"
+			resp, err := webhooktest.Delete(ns).Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		})
+		It("should allow overrides of users and groups", func() {
+			resp, err := webhooktest.Delete(ns).AsUser("admin").Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+
+			resp, err = webhooktest.Delete(ns).AsUser("foo", "system:masters").Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		})
+		It("should deny the removal of the protection", func() {
+			unprotected := ns.DeepCopy()
+			unprotected.Annotations = nil
+
+			resp, err := webhooktest.Update(unprotected, ns).Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDenied())
+
+			resp, err = webhooktest.Update(unprotected, ns).AsUser("admin").Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+
+			resp, err = webhooktest.Update(ns, unprotected).Validate(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		})
+		It("should protect objects of all kinds", func() {
+			validating, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+				ForAll().
+				WithLazyDecoding().
+				WithValidatePath("/validate-deletion-protection").
+				Build(protection)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			resp, err := webhooktest.Delete(ns).Handle(validating)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDenied())
+		})
+	})
+	Context("FinalizerGuard", func() {
+		var (
+			guard *webhook.FinalizerGuard
+			cm    *corev1.ConfigMap
+		)
+		BeforeEach(func() {
+			guard = &webhook.FinalizerGuard{Finalizers: []string{"example.com/cleanup"}}
+			cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "foo",
+				Name:       "bar",
+				Finalizers: []string{"example.com/cleanup", "example.com/other"},
+			}}
+		})
+		It("should deny the removal of protected finalizers", func() {
+			updated := cm.DeepCopy()
+			updated.Finalizers = []string{"example.com/other"}
+
+			resp, err := webhooktest.Update(updated, cm).Validate(guard)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDeniedWithReason("Forbidden"))
+			Ω(resp.Result.Message).Should(ContainSubstring("finalizers example.com/cleanup must not be removed from ConfigMap foo/bar"))
+		})
+		It("should allow the removal of other finalizers", func() {
+			updated := cm.DeepCopy()
+			updated.Finalizers = []string{"example.com/cleanup"}
+
+			resp, err := webhooktest.Update(updated, cm).Validate(guard)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		})
+		It("should allow the removal of protected finalizers from terminating objects", func() {
+			now := metav1.Now()
+			cm.DeletionTimestamp = &now
+			updated := cm.DeepCopy()
+			updated.Finalizers = nil
+
+			resp, err := webhooktest.Update(updated, cm).Validate(guard)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		})
+	})
+})
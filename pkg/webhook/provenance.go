@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PatchProvenance records which webhook changed which top-level fields of an object and when.
+// The records of all webhooks are stored as JSON list in the provenance annotation of the mutated object.
+type PatchProvenance struct {
+	// Webhook is the path of the mutating webhook.
+	Webhook string `json:"webhook"`
+	// Kind is the GroupVersionKind of the mutated object.
+	Kind string `json:"kind"`
+	// Fields are the top-level fields changed by the webhook.
+	Fields []string `json:"fields"`
+	// Time is the time of the last mutation by the webhook.
+	Time metav1.Time `json:"time"`
+}
+
+// ParsePatchProvenance parses the provenance records of an annotation value.
+func ParsePatchProvenance(value string) ([]PatchProvenance, error) {
+	var records []PatchProvenance
+	if err := json.Unmarshal([]byte(value), &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// withPatchProvenance records the top-level fields changed by the patches in the provenance annotation.
+// The annotation is only written if the patches change anything besides the annotation itself, in order to
+// avoid reinvocation loops.
+func (h *handler) withPatchProvenance(req admission.Request, resp admission.Response) (admission.Response, error) {
+	fields := changedFields(resp.Patches, "/metadata/annotations/"+escapeJSONPointer(h.provenanceAnnotation))
+	if len(fields) == 0 {
+		return resp, nil
+	}
+
+	patched, err := ApplyJSONPatch(req.Object.Raw, resp.Patches)
+	if err != nil {
+		return resp, err
+	}
+
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(patched, &obj); err != nil {
+		return resp, err
+	}
+
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		obj["metadata"] = metadata
+	}
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+
+	// records of other webhooks are kept, invalid annotation values are overwritten
+	var records []PatchProvenance
+	if value, ok := annotations[h.provenanceAnnotation].(string); ok {
+		records, _ = ParsePatchProvenance(value)
+	}
+	record := PatchProvenance{
+		Webhook: h.name,
+		Kind:    req.Kind.String(),
+		Fields:  fields,
+		Time:    metav1.Now(),
+	}
+	replaced := false
+	for idx := range records {
+		if records[idx].Webhook == record.Webhook {
+			records[idx] = record
+			replaced = true
+		}
+	}
+	if !replaced {
+		records = append(records, record)
+	}
+
+	value, err := json.Marshal(records)
+	if err != nil {
+		return resp, err
+	}
+	annotations[h.provenanceAnnotation] = string(value)
+
+	marshalled, err := json.Marshal(obj)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.Patches, err = jsonpatch.CreatePatch(req.Object.Raw, marshalled)
+	return resp, err
+}
+
+// changedFields returns the sorted top-level fields changed by the patches, patches on the ignored path are skipped.
+func changedFields(patches []jsonpatch.JsonPatchOperation, ignored string) []string {
+	set := map[string]struct{}{}
+	for _, patch := range patches {
+		if patch.Path == ignored {
+			continue
+		}
+
+		field := strings.SplitN(strings.TrimPrefix(patch.Path, "/"), "/", 2)[0]
+		set[unescapeJSONPointer(field)] = struct{}{}
+	}
+
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func escapeJSONPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func unescapeJSONPointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
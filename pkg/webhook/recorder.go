@@ -0,0 +1,435 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	recordingFile   = "admission.jsonl"
+	recordingPrefix = "admission-"
+	recordingSuffix = ".jsonl"
+	redacted        = "REDACTED"
+)
+
+// WebhookType is the type of a webhook.
+type WebhookType string
+
+const (
+	// Validating is the type of validating webhooks.
+	Validating WebhookType = "validating"
+	// Mutating is the type of mutating webhooks.
+	Mutating WebhookType = "mutating"
+)
+
+// Recording is a recorded AdmissionRequest and the response produced by a webhook.
+type Recording struct {
+	// Time is the time the request was handled.
+	Time metav1.Time `json:"time"`
+	// Webhook is the path of the webhook.
+	Webhook string `json:"webhook"`
+	// Type is the type of the webhook.
+	Type WebhookType `json:"type"`
+	// Request is the AdmissionRequest.
+	Request admissionv1.AdmissionRequest `json:"request"`
+	// Response is the AdmissionResponse without the patch.
+	Response admissionv1.AdmissionResponse `json:"response"`
+	// Patches are the JSON patch operations of the response.
+	Patches []jsonpatch.JsonPatchOperation `json:"patches,omitempty"`
+	// Redacted are the JSON pointers of the redacted fields, e.g. '/data'.
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+// RecorderOptions are the options of a Recorder.
+type RecorderOptions struct {
+	// Dir is the directory of the recording files.
+	Dir string
+	// MaxSize is the size in bytes after which the recording file is rotated, default is 10 MiB.
+	MaxSize int64
+	// MaxFiles is the number of rotated recording files which are kept, default is 5.
+	MaxFiles int
+	// RedactSecrets replaces the values of the data and stringData of Secrets.
+	RedactSecrets bool
+	// RedactFields are dot separated paths of fields, e.g. 'spec.template', whose values are replaced in all objects.
+	RedactFields []string
+	// BufferSize is the number of recordings which are buffered until they are written, default is 1000. Recordings
+	// are dropped if the buffer is full.
+	BufferSize int
+}
+
+// Recorder writes the AdmissionRequests and the responses of webhooks as JSON lines to a rotating local file store.
+// The recordings are written in the background, so the recording doesn't delay the responses of the webhooks.
+type Recorder struct {
+	opts RecorderOptions
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan Recording
+	pending sync.WaitGroup
+	done    chan struct{}
+
+	file *os.File
+	size int64
+}
+
+// NewRecorder returns a new Recorder which writes to the directory of the options.
+func NewRecorder(opts RecorderOptions) (*Recorder, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10 * 1024 * 1024
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = 5
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1000
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		opts:  opts,
+		queue: make(chan Recording, opts.BufferSize),
+		done:  make(chan struct{}),
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	go r.run()
+
+	return r, nil
+}
+
+// Record queues the request and the response of the webhook to be written to the current recording file. An error
+// is returned if the recording is dropped because the buffer is full or the Recorder is closed.
+func (r *Recorder) Record(webhook string, typ WebhookType, req admission.Request, resp admission.Response) error {
+	recording := Recording{
+		Time:     metav1.Now(),
+		Webhook:  webhook,
+		Type:     typ,
+		Request:  req.AdmissionRequest,
+		Response: resp.AdmissionResponse,
+		Patches:  resp.Patches,
+	}
+
+	// the raw patch of the response is recorded as patches, so it is redacted as well
+	recording.Response.Patch = nil
+	recording.Response.PatchType = nil
+	if len(resp.Patch) > 0 {
+		var patches []jsonpatch.JsonPatchOperation
+		if err := json.Unmarshal(resp.Patch, &patches); err != nil {
+			return fmt.Errorf("failed to decode patch of response: %w", err)
+		}
+		recording.Patches = append(append([]jsonpatch.JsonPatchOperation{}, recording.Patches...), patches...)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return fmt.Errorf("recorder is closed")
+	}
+
+	r.pending.Add(1)
+	select {
+	case r.queue <- recording:
+		return nil
+	default:
+		r.pending.Done()
+		return fmt.Errorf("recording buffer is full, recording of request %s is dropped", req.UID)
+	}
+}
+
+// Flush blocks until the queued recordings are written.
+func (r *Recorder) Flush() {
+	r.pending.Wait()
+}
+
+// Close writes the queued recordings and closes the current recording file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	<-r.done
+	return r.file.Close()
+}
+
+// run writes the queued recordings until the Recorder is closed.
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	for recording := range r.queue {
+		if err := r.write(recording); err != nil {
+			log.Log.Error(err, "failed to write admission recording", "webhook", recording.Webhook, "uid", recording.Request.UID)
+		}
+		r.pending.Done()
+	}
+}
+
+// write redacts the recording and appends it to the current recording file.
+func (r *Recorder) write(recording Recording) error {
+	if err := r.redact(&recording); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(recording)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if r.size > 0 && r.size+int64(len(line)) > r.opts.MaxSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+func (r *Recorder) open() error {
+	file, err := os.OpenFile(filepath.Join(r.opts.Dir, recordingFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate renames the current recording file, removes the oldest rotated files and opens a new recording file.
+func (r *Recorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := recordingPrefix + time.Now().UTC().Format("20060102T150405.000000000") + recordingSuffix
+	if err := os.Rename(filepath.Join(r.opts.Dir, recordingFile), filepath.Join(r.opts.Dir, rotated)); err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(r.opts.Dir, recordingPrefix+"*"+recordingSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for len(files) > r.opts.MaxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+
+	return r.open()
+}
+
+// redact replaces the values of the redacted fields of the options in the recording.
+func (r *Recorder) redact(recording *Recording) error {
+	var paths []string
+	for _, field := range r.opts.RedactFields {
+		paths = append(paths, "/"+strings.ReplaceAll(field, ".", "/"))
+	}
+	if r.opts.RedactSecrets && recording.Request.Kind.Group == "" && recording.Request.Kind.Kind == "Secret" {
+		paths = append(paths, "/data", "/stringData")
+	}
+
+	return recording.Redact(paths)
+}
+
+// Redact replaces the values at the JSON pointers, e.g. '/data', in the objects and the patches of the recording.
+// The replaced values are replaced in the message, the warnings and the audit annotations of the response as well.
+func (r *Recording) Redact(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var values []string
+	for _, raw := range []*[]byte{&r.Request.Object.Raw, &r.Request.OldObject.Raw} {
+		if len(*raw) == 0 {
+			continue
+		}
+
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal(*raw, &obj); err != nil {
+			return err
+		}
+		for _, path := range paths {
+			values = append(values, redactPath(obj, strings.Split(strings.TrimPrefix(path, "/"), "/"))...)
+		}
+
+		var err error
+		if *raw, err = json.Marshal(obj); err != nil {
+			return err
+		}
+	}
+
+	patches := make([]jsonpatch.JsonPatchOperation, 0, len(r.Patches))
+	for _, patch := range r.Patches {
+		for _, path := range paths {
+			switch {
+			case patch.Value == nil:
+			case patch.Path == path || strings.HasPrefix(patch.Path, path+"/"):
+				values = append(values, stringValues(patch.Value)...)
+				patch.Value = redacted
+			case patch.Path == "" || strings.HasPrefix(path, patch.Path+"/"):
+				// the value of the patch contains the redacted path, it is copied since it is shared with the response
+				value, replaced, err := redactValue(patch.Value, strings.Split(strings.TrimPrefix(path, patch.Path+"/"), "/"))
+				if err != nil {
+					return err
+				}
+				patch.Value = value
+				values = append(values, replaced...)
+			}
+		}
+		patches = append(patches, patch)
+	}
+	r.Patches = patches
+	r.Redacted = paths
+
+	r.redactResponse(values)
+	return nil
+}
+
+// redactResponse replaces the values in the message, the warnings and the audit annotations of the response, they are
+// copied since they are shared with the response.
+func (r *Recording) redactResponse(values []string) {
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	var replacements []string
+	for _, value := range values {
+		if value != "" && value != redacted {
+			replacements = append(replacements, value, redacted)
+		}
+	}
+	if len(replacements) == 0 {
+		return
+	}
+	replacer := strings.NewReplacer(replacements...)
+
+	if r.Response.Result != nil {
+		result := r.Response.Result.DeepCopy()
+		result.Message = replacer.Replace(result.Message)
+		if result.Details != nil {
+			for i := range result.Details.Causes {
+				result.Details.Causes[i].Message = replacer.Replace(result.Details.Causes[i].Message)
+			}
+		}
+		r.Response.Result = result
+	}
+
+	if len(r.Response.Warnings) > 0 {
+		warnings := make([]string, 0, len(r.Response.Warnings))
+		for _, warning := range r.Response.Warnings {
+			warnings = append(warnings, replacer.Replace(warning))
+		}
+		r.Response.Warnings = warnings
+	}
+
+	if len(r.Response.AuditAnnotations) > 0 {
+		annotations := make(map[string]string, len(r.Response.AuditAnnotations))
+		for key, value := range r.Response.AuditAnnotations {
+			annotations[key] = replacer.Replace(value)
+		}
+		r.Response.AuditAnnotations = annotations
+	}
+}
+
+// redactValue returns a copy of the value of a patch in which the value at the path is replaced and the replaced values.
+func redactValue(value interface{}, path []string) (interface{}, []string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var copied interface{}
+	if err := json.Unmarshal(raw, &copied); err != nil {
+		return nil, nil, err
+	}
+
+	var replaced []string
+	if obj, ok := copied.(map[string]interface{}); ok {
+		replaced = redactPath(obj, path)
+	}
+
+	return copied, replaced, nil
+}
+
+// redactPath replaces the value at the path and returns the replaced values, the values of a map are replaced
+// individually in order to keep the keys.
+func redactPath(obj map[string]interface{}, path []string) []string {
+	value, ok := obj[path[0]]
+	if !ok {
+		return nil
+	}
+
+	if len(path) > 1 {
+		if nested, ok := value.(map[string]interface{}); ok {
+			return redactPath(nested, path[1:])
+		}
+		return nil
+	}
+
+	if nested, ok := value.(map[string]interface{}); ok {
+		var replaced []string
+		for key := range nested {
+			replaced = append(replaced, stringValues(nested[key])...)
+			nested[key] = redacted
+		}
+		return replaced
+	}
+
+	obj[path[0]] = redacted
+	return stringValues(value)
+}
+
+// stringValues returns the strings contained in the value.
+func stringValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case map[string]interface{}:
+		var values []string
+		for _, nested := range v {
+			values = append(values, stringValues(nested)...)
+		}
+		return values
+	case []interface{}:
+		var values []string
+		for _, nested := range v {
+			values = append(values, stringValues(nested)...)
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,229 @@
+package webhook_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+func readRecordings(file string) []webhook.Recording {
+	f, err := os.Open(file)
+	Ω(err).ShouldNot(HaveOccurred())
+	defer f.Close()
+
+	var recordings []webhook.Recording
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		recording := webhook.Recording{}
+		Ω(json.Unmarshal(scanner.Bytes(), &recording)).Should(Succeed())
+		recordings = append(recordings, recording)
+	}
+	Ω(scanner.Err()).ShouldNot(HaveOccurred())
+
+	return recordings
+}
+
+var _ = Describe("Recorder", func() {
+	var (
+		dir    string
+		scheme *runtime.Scheme
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "recordings")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		scheme = runtime.NewScheme()
+		Ω(corev1.AddToScheme(scheme)).Should(Succeed())
+	})
+	AfterEach(func() {
+		Ω(os.RemoveAll(dir)).Should(Succeed())
+	})
+	It("should record requests and responses of webhook", func() {
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{Dir: dir})
+		Ω(err).ShouldNot(HaveOccurred())
+		defer recorder.Close()
+
+		_, h, err := webhook.NewGenericWebhook(scheme).
+			For(&corev1.Pod{}).
+			WithRecorder(recorder).
+			Build(&webhook.MutateFunc{
+				Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+					obj.(*corev1.Pod).Spec.NodeName = "jin"
+					return admission.Allowed("")
+				},
+			})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		raw, err := json.Marshal(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+		Ω(err).ShouldNot(HaveOccurred())
+		resp := h.Handle(context.TODO(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UID:       "uid",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		})
+		Ω(resp.Allowed).Should(BeTrue())
+
+		recorder.Flush()
+		recordings := readRecordings(filepath.Join(dir, "admission.jsonl"))
+		Ω(recordings).Should(HaveLen(1))
+		Ω(recordings[0].Webhook).Should(Equal("/mutate--v1-pod"))
+		Ω(recordings[0].Type).Should(Equal(webhook.Mutating))
+		Ω(recordings[0].Request.UID).Should(BeEquivalentTo("uid"))
+		Ω(recordings[0].Request.Object.Raw).Should(MatchJSON(raw))
+		Ω(recordings[0].Response.Allowed).Should(BeTrue())
+		Ω(recordings[0].Patches).Should(HaveLen(1))
+		Ω(recordings[0].Patches[0].Path).Should(Equal("/spec/nodeName"))
+	})
+	It("should redact secrets and fields", func() {
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{
+			Dir:           dir,
+			RedactSecrets: true,
+			RedactFields:  []string{"metadata.annotations"},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		defer recorder.Close()
+
+		raw, err := json.Marshal(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{"token": "secret"}},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		req := admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+				Operation: admissionv1.Update,
+				Object:    runtime.RawExtension{Raw: raw},
+				OldObject: runtime.RawExtension{Raw: raw},
+			},
+		}
+		resp := admission.PatchResponseFromRaw(raw, []byte(`{"metadata":{"name":"foo"},"data":{"password":"b3RoZXI="}}`))
+		Ω(resp.Patches).ShouldNot(BeEmpty())
+		Ω(recorder.Record("/mutate--v1-secret", webhook.Mutating, req, resp)).Should(Succeed())
+		recorder.Flush()
+
+		recordings := readRecordings(filepath.Join(dir, "admission.jsonl"))
+		Ω(recordings).Should(HaveLen(1))
+		Ω(string(recordings[0].Request.Object.Raw)).ShouldNot(ContainSubstring("c2VjcmV0"))
+		Ω(string(recordings[0].Request.Object.Raw)).ShouldNot(ContainSubstring(`"secret"`))
+		Ω(string(recordings[0].Request.Object.Raw)).Should(ContainSubstring(`"password":"REDACTED"`))
+		Ω(string(recordings[0].Request.Object.Raw)).Should(ContainSubstring(`"token":"REDACTED"`))
+		Ω(string(recordings[0].Request.OldObject.Raw)).Should(ContainSubstring(`"password":"REDACTED"`))
+		for _, patch := range recordings[0].Patches {
+			if patch.Value != nil {
+				Ω(patch.Value).Should(Equal("REDACTED"))
+			}
+		}
+	})
+	It("should redact the values of redacted fields in the response", func() {
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{
+			Dir:          dir,
+			RedactFields: []string{"metadata.annotations"},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		defer recorder.Close()
+
+		raw, err := json.Marshal(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{"token": "s3cr3t"}},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		req := admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		}
+		resp := admission.Denied("token s3cr3t is invalid").WithWarnings("token s3cr3t expires soon")
+		resp.AuditAnnotations = map[string]string{"token": "s3cr3t"}
+		Ω(recorder.Record("/validate--v1-configmap", webhook.Validating, req, resp)).Should(Succeed())
+		recorder.Flush()
+
+		recordings := readRecordings(filepath.Join(dir, "admission.jsonl"))
+		Ω(recordings).Should(HaveLen(1))
+		Ω(recordings[0].Redacted).Should(Equal([]string{"/metadata/annotations"}))
+		Ω(recordings[0].Response.Result.Message).Should(Equal("token REDACTED is invalid"))
+		Ω(recordings[0].Response.Warnings).Should(Equal([]string{"token REDACTED expires soon"}))
+		Ω(recordings[0].Response.AuditAnnotations).Should(Equal(map[string]string{"token": "REDACTED"}))
+		Ω(resp.Result.Message).Should(Equal("token s3cr3t is invalid"))
+	})
+	It("should rotate recording files", func() {
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{
+			Dir:      dir,
+			MaxSize:  1,
+			MaxFiles: 2,
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		defer recorder.Close()
+
+		for i := 0; i < 5; i++ {
+			Ω(recorder.Record("/validate--v1-pod", webhook.Validating, admission.Request{}, admission.Allowed(""))).Should(Succeed())
+		}
+		recorder.Flush()
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(files).Should(HaveLen(3))
+		Ω(readRecordings(filepath.Join(dir, "admission.jsonl"))).Should(HaveLen(1))
+	})
+	It("should redact raw patches and values of parent paths", func() {
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{
+			Dir:           dir,
+			RedactSecrets: true,
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		defer recorder.Close()
+
+		req := admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+				Operation: admissionv1.Create,
+			},
+		}
+		value := map[string]interface{}{"data": map[string]interface{}{"password": "c2VjcmV0"}}
+		resp := admission.Allowed("")
+		resp.Patches = []jsonpatch.JsonPatchOperation{{Operation: "replace", Path: "", Value: value}}
+		patchType := admissionv1.PatchTypeJSONPatch
+		resp.PatchType = &patchType
+		resp.Patch = []byte(`[{"op":"add","path":"/stringData","value":{"token":"secret"}}]`)
+		Ω(recorder.Record("/mutate--v1-secret", webhook.Mutating, req, resp)).Should(Succeed())
+		recorder.Flush()
+
+		recordings := readRecordings(filepath.Join(dir, "admission.jsonl"))
+		Ω(recordings).Should(HaveLen(1))
+		Ω(recordings[0].Response.Patch).Should(BeEmpty())
+		Ω(recordings[0].Patches).Should(HaveLen(2))
+		Ω(recordings[0].Patches[0].Value).Should(Equal(map[string]interface{}{"data": map[string]interface{}{"password": "REDACTED"}}))
+		Ω(recordings[0].Patches[1].Value).Should(Equal("REDACTED"))
+		Ω(value["data"]).Should(Equal(map[string]interface{}{"password": "c2VjcmV0"}))
+	})
+	It("should write queued recordings on close", func() {
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{Dir: dir})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		for i := 0; i < 3; i++ {
+			Ω(recorder.Record("/validate--v1-pod", webhook.Validating, admission.Request{}, admission.Allowed(""))).Should(Succeed())
+		}
+		Ω(recorder.Close()).Should(Succeed())
+		Ω(recorder.Record("/validate--v1-pod", webhook.Validating, admission.Request{}, admission.Allowed(""))).ShouldNot(Succeed())
+
+		Ω(readRecordings(filepath.Join(dir, "admission.jsonl"))).Should(HaveLen(3))
+	})
+})
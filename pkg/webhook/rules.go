@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// supportedOperations are the operations which are handled by the generic handler.
+var supportedOperations = []admissionv1.Operation{admissionv1.Create, admissionv1.Update, admissionv1.Delete}
+
+// Rules returns the rules of the webhook configuration, i.e. the operations and the resources of the handled kinds.
+// The resource is resolved by the RESTMapper of the manager respectively of the client and guessed otherwise.
+func (blder *Builder) Rules() ([]admissionregistrationv1.RuleWithOperations, error) {
+	operations := blder.operations
+	if len(operations) == 0 {
+		operations = supportedOperations
+	}
+
+	if blder.wildcard {
+		resources := []string{wildcardRule}
+		if blder.subresources {
+			resources = append(resources, wildcardRule+"/"+wildcardRule)
+		}
+
+		scope := admissionregistrationv1.NamespacedScope
+		if blder.scope != "" {
+			scope = blder.scope
+		}
+
+		return []admissionregistrationv1.RuleWithOperations{ruleWithOperations(admissionregistrationv1.Rule{
+			APIGroups:   []string{wildcardRule},
+			APIVersions: []string{wildcardRule},
+			Resources:   resources,
+			Scope:       &scope,
+		}, operations)}, nil
+	}
+
+	kinds, err := blder.kinds()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]admissionregistrationv1.RuleWithOperations, 0, len(kinds))
+	for _, gvk := range kinds {
+		gvr, err := blder.resourceFor(gvk)
+		if err != nil {
+			return nil, err
+		}
+
+		rule := admissionregistrationv1.Rule{
+			APIGroups:   []string{gvr.Group},
+			APIVersions: []string{gvr.Version},
+			Resources:   []string{gvr.Resource},
+		}
+		if blder.scope != "" {
+			scope := blder.scope
+			rule.Scope = &scope
+		}
+
+		rules = append(rules, ruleWithOperations(rule, operations))
+	}
+
+	return rules, nil
+}
+
+// MatchConditions returns the match conditions of the webhook configuration, i.e. one condition for each kind which
+// is excluded from a webhook which handles all kinds, in order that the API server doesn't call the webhook for the
+// excluded kinds at all. The conditions are named 'exclude-<kind>.<group>' and must be set alongside the Rules.
+// Webhooks which handle specific kinds have no match conditions.
+func (blder *Builder) MatchConditions() ([]admissionregistrationv1.MatchCondition, error) {
+	if !blder.wildcard {
+		return nil, nil
+	}
+
+	var conditions []admissionregistrationv1.MatchCondition
+	seen := map[schema.GroupKind]bool{}
+	for _, kind := range append(append([]schema.GroupKind{}, defaultExclusions...), blder.exclusions...) {
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+
+		name := "exclude-" + strings.ToLower(kind.Kind)
+		if kind.Group != "" {
+			name += "." + kind.Group
+		}
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid name of the match condition of the excluded kind %s: %s", kind, strings.Join(errs, ", "))
+		}
+
+		conditions = append(conditions, admissionregistrationv1.MatchCondition{
+			Name:       name,
+			Expression: fmt.Sprintf("!(request.kind.group == %q && request.kind.kind == %q)", kind.Group, kind.Kind),
+		})
+	}
+
+	return conditions, nil
+}
+
+// ruleWithOperations returns the rule for the operations.
+func ruleWithOperations(rule admissionregistrationv1.Rule, operations []admissionv1.Operation) admissionregistrationv1.RuleWithOperations {
+	ruleWithOperations := admissionregistrationv1.RuleWithOperations{Rule: rule}
+	for _, operation := range operations {
+		ruleWithOperations.Operations = append(ruleWithOperations.Operations, admissionregistrationv1.OperationType(operation))
+	}
+
+	return ruleWithOperations
+}
+
+// resourceFor returns the resource of the kind.
+func (blder *Builder) resourceFor(gvk schema.GroupVersionKind) (schema.GroupVersionResource, error) {
+	if mapper := blder.getRESTMapper(); mapper != nil {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err == nil {
+			return mapping.Resource, nil
+		} else if !meta.IsNoMatchError(err) {
+			return schema.GroupVersionResource{}, err
+		}
+	}
+
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return gvr, nil
+}
+
+// operationSet returns the set of the operations, nil if all operations are handled.
+func operationSet(operations []admissionv1.Operation) (map[admissionv1.Operation]bool, error) {
+	if len(operations) == 0 {
+		return nil, nil
+	}
+
+	set := map[admissionv1.Operation]bool{}
+	for _, operation := range operations {
+		supported := false
+		for _, s := range supportedOperations {
+			supported = supported || s == operation
+		}
+		if !supported {
+			return nil, fmt.Errorf("operation %q is not supported", operation)
+		}
+		set[operation] = true
+	}
+
+	return set, nil
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// ServerOptions are the options of a standalone webhook Server.
+type ServerOptions struct {
+	ctrlwebhook.Options
+
+	// Scheme is used to decode the objects of the requests, default is the scheme of the Kubernetes client-go.
+	Scheme *runtime.Scheme
+	// Client is injected into the webhooks, optional.
+	Client client.Client
+}
+
+// Server hosts generic webhooks over HTTPS without a manager.Manager, i.e. without kubeconfig, caches and leader
+// election. It serves liveness and readiness probes on '/healthz' and '/readyz' next to the webhooks and shuts down
+// gracefully when the context passed to Start is done. Server implements http.Handler in order to be used with
+// httptest.
+type Server struct {
+	ctrlwebhook.Server
+
+	scheme *runtime.Scheme
+	client client.Client
+
+	mux     *http.ServeMux
+	healthz *healthz.Handler
+	readyz  *healthz.Handler
+}
+
+// NewServer returns a new standalone webhook Server.
+func NewServer(opts ServerOptions) *Server {
+	if opts.Scheme == nil {
+		opts.Scheme = scheme.Scheme
+	}
+	if opts.WebhookMux == nil {
+		opts.WebhookMux = http.NewServeMux()
+	}
+
+	srv := &Server{
+		Server:  ctrlwebhook.NewServer(opts.Options),
+		scheme:  opts.Scheme,
+		client:  opts.Client,
+		mux:     opts.WebhookMux,
+		healthz: &healthz.Handler{Checks: map[string]healthz.Checker{"ping": healthz.Ping}},
+		readyz:  &healthz.Handler{Checks: map[string]healthz.Checker{}},
+	}
+	srv.readyz.Checks["webhook-server"] = srv.StartedChecker()
+
+	srv.mux.Handle(healthzPath, http.StripPrefix(healthzPath, srv.healthz))
+	srv.mux.Handle(healthzPath+"/", http.StripPrefix(healthzPath, srv.healthz))
+	srv.mux.Handle(readyzPath, http.StripPrefix(readyzPath, srv.readyz))
+	srv.mux.Handle(readyzPath+"/", http.StripPrefix(readyzPath, srv.readyz))
+
+	return srv
+}
+
+// AddHealthzCheck adds a liveness check to the server, it must be called before the server is started.
+func (s *Server) AddHealthzCheck(name string, check healthz.Checker) error {
+	return addCheck(s.healthz, name, check)
+}
+
+// AddReadyzCheck adds a readiness check to the server, it must be called before the server is started.
+func (s *Server) AddReadyzCheck(name string, check healthz.Checker) error {
+	return addCheck(s.readyz, name, check)
+}
+
+// WebhookMux returns the multiplexer of the server.
+func (s *Server) WebhookMux() *http.ServeMux {
+	return s.mux
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func addCheck(handler *healthz.Handler, name string, check healthz.Checker) error {
+	if _, ok := handler.Checks[name]; ok {
+		return fmt.Errorf("check with name %q already exists", name)
+	}
+
+	handler.Checks[name] = check
+	return nil
+}
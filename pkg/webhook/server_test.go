@@ -0,0 +1,138 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		srv *webhook.Server
+	)
+	BeforeEach(func() {
+		srv = webhook.NewServer(webhook.ServerOptions{
+			Client: fake.NewClientBuilder().Build(),
+		})
+	})
+	It("should serve webhooks", func() {
+		wh := &webhook.ValidateFuncs{
+			CreateFunc: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+				if obj.(*corev1.Pod).Name == "foo" {
+					return admission.Denied("foo is not allowed")
+				}
+				return admission.Allowed("")
+			},
+		}
+		err := webhook.NewGenericWebhookServedBy(srv).
+			For(&corev1.Pod{}).
+			Complete(wh)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(wh.Client).ShouldNot(BeNil())
+		Ω(wh.Decoder).ShouldNot(BeNil())
+
+		err = webhook.NewGenericWebhookServedBy(srv).
+			For(&corev1.Pod{}).
+			Complete(wh)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		server := httptest.NewServer(srv)
+		defer server.Close()
+
+		raw, err := json.Marshal(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+		Ω(err).ShouldNot(HaveOccurred())
+		body, err := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "uid",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		resp, err := http.Post(server.URL+"/validate--v1-pod", "application/json", bytes.NewReader(body))
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+
+		review := &admissionv1.AdmissionReview{}
+		Ω(json.NewDecoder(resp.Body).Decode(review)).Should(Succeed())
+		Ω(review.Response.UID).Should(BeEquivalentTo("uid"))
+		Ω(review.Response.Allowed).Should(BeFalse())
+		Ω(review.Response.Result.Message).Should(Equal("foo is not allowed"))
+	})
+	It("should serve health probes", func() {
+		Ω(srv.AddHealthzCheck("ping", nil)).ShouldNot(Succeed())
+		Ω(srv.AddReadyzCheck("custom", func(_ *http.Request) error { return nil })).Should(Succeed())
+
+		recorder := httptest.NewRecorder()
+		srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+
+		recorder = httptest.NewRecorder()
+		srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		Ω(recorder.Code).Should(Equal(http.StatusInternalServerError))
+
+		recorder = httptest.NewRecorder()
+		srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz/custom", nil))
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+	})
+	It("should start and shut down gracefully", func() {
+		dir, err := os.MkdirTemp("", "certs")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		crt, key, err := cert.GenerateSelfSignedCertKey("localhost", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(os.WriteFile(filepath.Join(dir, "tls.crt"), crt, 0o600)).Should(Succeed())
+		Ω(os.WriteFile(filepath.Join(dir, "tls.key"), key, 0o600)).Should(Succeed())
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+		port := listener.Addr().(*net.TCPAddr).Port
+		Ω(listener.Close()).Should(Succeed())
+
+		srv = webhook.NewServer(webhook.ServerOptions{
+			Options: ctrlwebhook.Options{
+				Host:    "127.0.0.1",
+				Port:    port,
+				CertDir: dir,
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- srv.Start(ctx)
+		}()
+
+		Eventually(func() int {
+			recorder := httptest.NewRecorder()
+			srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			return recorder.Code
+		}).Should(Equal(http.StatusOK))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
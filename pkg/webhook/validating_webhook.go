@@ -24,6 +24,11 @@ var _ Validator = &ValidatingWebhook{}
 type ValidatingWebhook struct {
 	InjectedClient
 	InjectedDecoder
+	InjectedEventRecorder
+	InjectedAPIReader
+	InjectedScheme
+	InjectedRESTMapper
+	InjectedLogger
 }
 
 // ValidateCreate implements the Validator interface.
@@ -6,22 +6,62 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// Compiler is implemented by webhooks which compile their rules or policies once, the Builder calls Compile after the
+// dependencies are injected and fails if the compilation fails.
+type Compiler interface {
+	Compile() error
+}
+
+// eventRecorderName is the name of the event recorder of the manager which is injected into webhooks.
+const eventRecorderName = "generic-webhook"
+
 // Builder builds a Webhook.
 type Builder struct {
 	mgr            manager.Manager
+	server         *Server
+	scheme         *runtime.Scheme
+	client         client.Client
+	apiReader      client.Reader
 	apiType        runtime.Object
+	metadataKinds  []schema.GroupVersionKind
+	wildcard       bool
+	exclusions     []schema.GroupKind
+	subresources   bool
+	scope          admissionregistrationv1.ScopeType
 	pathValidate   string
 	pathMutate     string
 	prefixValidate string
 	prefixMutate   string
+
+	provenanceAnnotation string
+	recorder             *Recorder
+	enforcement          *EnforcementSwitch
+	enforcementMode      EnforcementMode
+	enforcementOverrides *EnforcementOverrides
+	config               ConfigProvider
+	eventRecorder        record.EventRecorder
+	events               *EventOptions
+	indexes              []index
+	informers            []client.Object
+	operations           []admissionv1.Operation
+	lazyDecoding         bool
+	ignoredChanges       []DiffCategory
 }
 
 // NewGenericWebhookManagedBy returns a new webhook Builder that will be invoked by the provided manager.Manager.
@@ -33,9 +73,88 @@ func NewGenericWebhookManagedBy(mgr manager.Manager) *Builder {
 	}
 }
 
+// NewGenericWebhookServedBy returns a new webhook Builder that will be invoked by the provided standalone Server.
+func NewGenericWebhookServedBy(srv *Server) *Builder {
+	return &Builder{
+		server:         srv,
+		scheme:         srv.scheme,
+		client:         srv.client,
+		prefixMutate:   "/mutate-",
+		prefixValidate: "/validate-",
+	}
+}
+
+// NewGenericWebhook returns a new webhook Builder which is not managed by a manager.Manager.
+// The admission handlers of such a webhook are obtained by Build, e.g. to serve or test them without a manager.
+func NewGenericWebhook(scheme *runtime.Scheme) *Builder {
+	return &Builder{
+		scheme:         scheme,
+		prefixMutate:   "/mutate-",
+		prefixValidate: "/validate-",
+	}
+}
+
 // For takes a runtime.Object which should be a CR.
 func (blder *Builder) For(apiType runtime.Object) *Builder {
 	blder.apiType = apiType
+	blder.wildcard = false
+	return blder
+}
+
+// ForMetadata takes the kinds of the objects whose metadata is handled by the webhook. The objects are decoded into
+// *metav1.PartialObjectMetadata regardless of their kind, the patches of a mutating webhook are generated from the
+// metadata only. The paths of webhooks of multiple kinds must be set by WithValidatePath respectively WithMutatePath.
+func (blder *Builder) ForMetadata(gvks ...schema.GroupVersionKind) *Builder {
+	blder.apiType = &metav1.PartialObjectMetadata{}
+	blder.metadataKinds = gvks
+	blder.wildcard = false
+	return blder
+}
+
+// ForAll handles the metadata of the objects of all kinds like ForMetadata, i.e. the rules of the webhook
+// configuration match all namespaced resources unless the scope is set by WithScope. Subresources are only handled if
+// they are enabled by WithSubresources. Requests of the kinds which impair the stability of the cluster, e.g. leases,
+// events or token reviews, as well as of the kinds excluded by WithExclusions are allowed without invoking the webhook.
+// The exclusions are also enforced by the API server if the MatchConditions are set in the webhook configuration.
+// The paths of the webhook must be set by WithValidatePath respectively WithMutatePath.
+func (blder *Builder) ForAll() *Builder {
+	blder.apiType = &metav1.PartialObjectMetadata{}
+	blder.metadataKinds = nil
+	blder.wildcard = true
+	return blder
+}
+
+// WithExclusions excludes the kinds in addition to the default exclusions from a webhook which handles all kinds, see
+// MatchConditions.
+func (blder *Builder) WithExclusions(kinds ...schema.GroupKind) *Builder {
+	blder.exclusions = append(blder.exclusions, kinds...)
+	return blder
+}
+
+// WithSubresources handles the subresources of all kinds in addition to the resources of a webhook which handles all
+// kinds, e.g. 'pods/status' or 'deployments/scale'.
+func (blder *Builder) WithSubresources() *Builder {
+	blder.subresources = true
+	return blder
+}
+
+// WithScope sets the scope of the resources matched by the rules of the webhook configuration, the rules of a webhook
+// which handles all kinds match namespaced resources by default.
+func (blder *Builder) WithScope(scope admissionregistrationv1.ScopeType) *Builder {
+	blder.scope = scope
+	return blder
+}
+
+// WithClient sets the client.Client which is injected into the webhook if it is not managed by a manager.Manager.
+func (blder *Builder) WithClient(client client.Client) *Builder {
+	blder.client = client
+	return blder
+}
+
+// WithAPIReader sets the uncached client.Reader which is injected into the webhook, default is the API reader of the
+// manager. Webhooks which are not managed by a manager don't get an API reader injected unless it is set.
+func (blder *Builder) WithAPIReader(reader client.Reader) *Builder {
+	blder.apiReader = reader
 	return blder
 }
 
@@ -63,53 +182,305 @@ func (blder *Builder) WithValidatePrefix(prefix string) *Builder {
 	return blder
 }
 
-// Complete builds the webhook.
+// WithOperations restricts the webhook to the given operations, requests of other operations are allowed without
+// decoding the objects and without invoking the webhook. The Rules of the webhook configuration only list these
+// operations, default are CREATE, UPDATE and DELETE.
+func (blder *Builder) WithOperations(operations ...admissionv1.Operation) *Builder {
+	blder.operations = operations
+	return blder
+}
+
+// WithLazyDecoding defers the decoding of the objects of a request to their first access by Objects. The validator is
+// invoked with nil objects and reads them from Objects(ctx) instead, e.g. only their metadata. The mutator is invoked
+// with the decoded object, only the old object is decoded lazily. The webhook fails to build unless it implements the
+// LazyDecoder interface, e.g. by embedding LazyDecoding.
+func (blder *Builder) WithLazyDecoding() *Builder {
+	blder.lazyDecoding = true
+	return blder
+}
+
+// WithIgnoredChanges skips the validator on updates which only change paths of the given categories, e.g. DiffStatus,
+// such updates are allowed without decoding the objects. Updates which change nothing are skipped as well.
+func (blder *Builder) WithIgnoredChanges(categories ...DiffCategory) *Builder {
+	blder.ignoredChanges = categories
+	return blder
+}
+
+// WithPatchProvenance enables recording of the patch provenance of the mutating webhook in the given annotation.
+// The annotation holds a JSON list of PatchProvenance records, one per webhook which changed the object.
+func (blder *Builder) WithPatchProvenance(annotation string) *Builder {
+	blder.provenanceAnnotation = annotation
+	return blder
+}
+
+// WithRecorder records the requests and the responses of the webhook with the given Recorder.
+func (blder *Builder) WithRecorder(recorder *Recorder) *Builder {
+	blder.recorder = recorder
+	return blder
+}
+
+// WithEnforcement sets the EnforcementMode of the validating webhook, default is Enforce. The webhook fails to build
+// if the mode is invalid.
+func (blder *Builder) WithEnforcement(mode EnforcementMode) *Builder {
+	blder.enforcement = nil
+	blder.enforcementMode = mode
+	return blder
+}
+
+// WithEnforcementSwitch sets the EnforcementSwitch of the validating webhook in order to switch the EnforcementMode
+// at runtime, e.g. by the EnforcementSwitch.WatchConfigMap.
+func (blder *Builder) WithEnforcementSwitch(enforcement *EnforcementSwitch) *Builder {
+	blder.enforcement = enforcement
+	blder.enforcementMode = ""
+	return blder
+}
+
+// WithEnforcementOverrides enables the overrides of the EnforcementMode of the validating webhook by labels of
+// namespaces and annotations of objects. The namespaces are read from the cache of the manager or from the client of
+// the webhook if it is not managed by a manager.
+func (blder *Builder) WithEnforcementOverrides(overrides EnforcementOverrides) *Builder {
+	blder.enforcementOverrides = &overrides
+	return blder
+}
+
+// WithEventRecorder sets the record.EventRecorder which is injected into the webhook, default is the event recorder
+// of the manager.
+func (blder *Builder) WithEventRecorder(recorder record.EventRecorder) *Builder {
+	blder.eventRecorder = recorder
+	return blder
+}
+
+// WithEvents enables Kubernetes Events for denied or mutated requests, which are emitted by the event recorder on
+// the controller owner of the object, on the object itself if it already exists or on its namespace.
+func (blder *Builder) WithEvents(opts EventOptions) *Builder {
+	blder.events = &opts
+	return blder
+}
+
+// WithIndex declares a field index which is required by the webhook, e.g. to efficiently list objects by a field in
+// order to check uniqueness or references. The index is registered in the cache of the manager by Complete.
+func (blder *Builder) WithIndex(obj client.Object, field string, extractor client.IndexerFunc) *Builder {
+	blder.indexes = append(blder.indexes, index{obj: obj, field: field, extractor: extractor})
+	return blder
+}
+
+// WithInformer declares an informer which is required by the webhook. The informer is registered in the cache of the
+// manager by Complete and the readiness check of the manager fails until all informers of the webhook are synced.
+func (blder *Builder) WithInformer(obj client.Object) *Builder {
+	blder.informers = append(blder.informers, obj)
+	return blder
+}
+
+// WithConfig sets the ConfigProvider, e.g. a ConfigSource, which is injected into the webhook.
+func (blder *Builder) WithConfig(config ConfigProvider) *Builder {
+	blder.config = config
+	return blder
+}
+
+// Complete builds the webhook and registers it in the webhook server of the manager or in the standalone Server.
+// Liveness and readiness checks are added for each registered webhook, see registerChecks.
 // If the given object implements the Mutator interface, a MutatingWebhook will be created.
 // If the given object implements the Validator interface, a ValidatingWebhook will be created.
 func (blder *Builder) Complete(i interface{}) error {
+	if blder.mgr == nil && blder.server == nil {
+		return fmt.Errorf("webhook is neither managed by a manager nor served by a server, use Build instead")
+	}
+
+	webhooks, err := blder.build(i)
+	if err != nil {
+		return err
+	}
+
+	// webhooks of paths which are already handled are skipped together with their caches and checks
+	var unregistered []*handler
+	for _, w := range webhooks {
+		if !isAlreadyHandled(blder.webhookServer(), w.name) {
+			unregistered = append(unregistered, w)
+		}
+	}
+	if len(unregistered) == 0 {
+		return nil
+	}
+
+	informers, err := blder.registerCaches()
+	if err != nil {
+		return err
+	}
 
+	for _, w := range unregistered {
+		if !blder.register(w.name, &admission.Webhook{Handler: w}) {
+			continue
+		}
+		if err := blder.registerChecks(w.name, informers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Build builds the admission handlers of the webhook without registering them.
+// The validating handler is nil if the given object does not implement the Validator interface and the mutating
+// handler is nil if it does not implement the Mutator interface.
+func (blder *Builder) Build(i interface{}) (validating admission.Handler, mutating admission.Handler, err error) {
+	webhooks, err := blder.build(i)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, w := range webhooks {
+		if w.validator != nil {
+			validating = w
+		} else {
+			mutating = w
+		}
+	}
+
+	return validating, mutating, nil
+}
+
+// Handles returns true if the webhook handles objects of the given GroupVersionKind.
+func (blder *Builder) Handles(gvk schema.GroupVersionKind) bool {
+	if blder.wildcard {
+		return !exclusionSet(blder.exclusions)[gvk.GroupKind()]
+	}
+
+	kinds, err := blder.kinds()
+	if err != nil {
+		return false
+	}
+
+	for _, kind := range kinds {
+		if kind == gvk {
+			return true
+		}
+	}
+
+	return false
+}
+
+// kinds returns the kinds of the objects handled by the webhook.
+func (blder *Builder) kinds() ([]schema.GroupVersionKind, error) {
+	if _, ok := blder.apiType.(*metav1.PartialObjectMetadata); ok {
+		if len(blder.metadataKinds) == 0 {
+			return nil, fmt.Errorf("kinds of the metadata webhook must not be empty")
+		}
+		return blder.metadataKinds, nil
+	}
+
+	if blder.apiType == nil || blder.getScheme() == nil {
+		return nil, fmt.Errorf("api type and scheme of the webhook must not be nil")
+	}
+
+	gvk, err := apiutil.GVKForObject(blder.apiType, blder.getScheme())
+	if err != nil {
+		return nil, err
+	}
+
+	return []schema.GroupVersionKind{gvk}, nil
+}
+
+func (blder *Builder) build(i interface{}) ([]*handler, error) {
 	if blder.pathMutate != "" && !strings.HasPrefix(blder.pathMutate, "/") {
-		return fmt.Errorf("mutating path %q must start with '/'", blder.pathMutate)
+		return nil, fmt.Errorf("mutating path %q must start with '/'", blder.pathMutate)
 	} else if !strings.HasPrefix(blder.prefixMutate, "/") {
-		return fmt.Errorf("mutating prefix %q must start with '/'", blder.prefixMutate)
+		return nil, fmt.Errorf("mutating prefix %q must start with '/'", blder.prefixMutate)
 	}
 	if blder.pathValidate != "" && !strings.HasPrefix(blder.pathValidate, "/") {
-		return fmt.Errorf("validating path %q must start with '/'", blder.pathValidate)
+		return nil, fmt.Errorf("validating path %q must start with '/'", blder.pathValidate)
 	} else if !strings.HasPrefix(blder.prefixValidate, "/") {
-		return fmt.Errorf("validating prefix %q must start with '/'", blder.prefixValidate)
+		return nil, fmt.Errorf("validating prefix %q must start with '/'", blder.prefixValidate)
+	}
+
+	if blder.getScheme() == nil {
+		return nil, fmt.Errorf("scheme of the webhook must not be nil")
+	}
+	decoder := admission.NewDecoder(blder.getScheme())
+
+	operations, err := operationSet(blder.operations)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := i.(LazyDecoder); blder.lazyDecoding && !ok {
+		return nil, fmt.Errorf("webhook instance %T must implement the LazyDecoder interface if lazy decoding is enabled", i)
+	}
+
+	var exclusions map[schema.GroupKind]bool
+	if blder.wildcard {
+		exclusions = exclusionSet(blder.exclusions)
 	}
 
-	decoder := admission.NewDecoder(blder.mgr.GetScheme())
+	enforcement := blder.enforcement
+	if blder.enforcementMode != "" {
+		if enforcement, err = NewEnforcementSwitch(blder.enforcementMode); err != nil {
+			return nil, err
+		}
+	}
 
-	isWebhook := false
+	var events *eventEmitter
+	if blder.events != nil {
+		if blder.getEventRecorder() == nil {
+			return nil, fmt.Errorf("event recorder of the webhook must not be nil if events are enabled")
+		}
+		events = newEventEmitter(blder.getEventRecorder(), *blder.events)
+	}
+
+	var webhooks []*handler
 	if validator, ok := i.(Validator); ok {
-		w := &admission.Webhook{
-			Handler: withValidationHandler(validator, blder.apiType, decoder),
+		path, err := blder.ValidatingPath()
+		if err != nil {
+			return nil, err
 		}
 
-		if err := blder.registerValidatingWebhook(w); err != nil {
-			return err
+		h := withValidationHandler(validator, blder.apiType, decoder)
+		h.name = path
+		h.operations = operations
+		h.ignoredChanges = blder.ignoredChanges
+		h.exclusions = exclusions
+		h.skipSubresources = blder.wildcard && !blder.subresources
+		h.lazyDecoding = blder.lazyDecoding
+		h.recorder = blder.recorder
+		h.enforcement = enforcement
+		h.events = events
+		if blder.enforcementOverrides != nil {
+			h.enforcementOverrides = blder.enforcementOverrides
+			h.namespaces = blder.getReader()
 		}
-		isWebhook = true
+
+		webhooks = append(webhooks, h)
 	}
 
 	if mutator, ok := i.(Mutator); ok {
-		w := &admission.Webhook{
-			Handler: withMutationHandler(mutator, blder.apiType, decoder),
+		path, err := blder.MutatingPath()
+		if err != nil {
+			return nil, err
 		}
 
-		if err := blder.registerMutatingWebhook(w); err != nil {
-			return err
-		}
-		isWebhook = true
+		h := withMutationHandler(mutator, blder.apiType, decoder)
+		h.name = path
+		h.operations = operations
+		h.exclusions = exclusions
+		h.skipSubresources = blder.wildcard && !blder.subresources
+		h.lazyDecoding = blder.lazyDecoding
+		h.provenanceAnnotation = blder.provenanceAnnotation
+		h.recorder = blder.recorder
+		h.events = events
+
+		webhooks = append(webhooks, h)
 	}
 
-	if !isWebhook {
-		return fmt.Errorf("webhook instance %v does implement neither Mutator nor Validator interface", i)
+	if len(webhooks) == 0 {
+		return nil, fmt.Errorf("webhook instance %v does implement neither Mutator nor Validator interface", i)
 	}
 
-	if injector, ok := i.(ClientInjector); ok {
-		if err := injector.InjectClient(blder.mgr.GetClient()); err != nil {
+	return webhooks, blder.inject(i, decoder)
+}
+
+// inject injects the dependencies into the webhook instance and into all stages of a MutatorChain and compiles them.
+func (blder *Builder) inject(i interface{}, decoder admission.Decoder) error {
+	if injector, ok := i.(ClientInjector); ok && blder.getClient() != nil {
+		if err := injector.InjectClient(blder.getClient()); err != nil {
 			return err
 		}
 	}
@@ -120,49 +491,200 @@ func (blder *Builder) Complete(i interface{}) error {
 		}
 	}
 
-	return nil
-}
+	if injector, ok := i.(EventRecorderInjector); ok && blder.getEventRecorder() != nil {
+		if err := injector.InjectEventRecorder(blder.getEventRecorder()); err != nil {
+			return err
+		}
+	}
 
-func (blder *Builder) registerValidatingWebhook(w *admission.Webhook) error {
-	path := blder.pathValidate
-	if strings.TrimSpace(path) == "" {
-		gvk, err := apiutil.GVKForObject(blder.apiType, blder.mgr.GetScheme())
-		if err != nil {
+	if injector, ok := i.(APIReaderInjector); ok && blder.getAPIReader() != nil {
+		if err := injector.InjectAPIReader(blder.getAPIReader()); err != nil {
 			return err
 		}
+	}
 
-		path = generatePath(blder.prefixValidate, gvk)
+	if injector, ok := i.(SchemeInjector); ok {
+		if err := injector.InjectScheme(blder.getScheme()); err != nil {
+			return err
+		}
 	}
-	if !isAlreadyHandled(blder.mgr, path) {
-		blder.mgr.GetWebhookServer().Register(path, w)
+
+	if injector, ok := i.(RESTMapperInjector); ok && blder.getRESTMapper() != nil {
+		if err := injector.InjectRESTMapper(blder.getRESTMapper()); err != nil {
+			return err
+		}
 	}
 
-	return nil
-}
+	if injector, ok := i.(LoggerInjector); ok {
+		if err := injector.InjectLogger(blder.getLogger()); err != nil {
+			return err
+		}
+	}
 
-func (blder *Builder) registerMutatingWebhook(w *admission.Webhook) error {
-	path := blder.pathMutate
-	if strings.TrimSpace(path) == "" {
-		gvk, err := apiutil.GVKForObject(blder.apiType, blder.mgr.GetScheme())
-		if err != nil {
+	if injector, ok := i.(ConfigInjector); ok {
+		if blder.config == nil {
+			return fmt.Errorf("configuration of the webhook must not be nil, it is set by WithConfig")
+		}
+		if err := injector.InjectConfig(blder.config); err != nil {
+			return err
+		}
+	}
+
+	if compiler, ok := i.(Compiler); ok {
+		if err := compiler.Compile(); err != nil {
 			return err
 		}
+	}
+
+	if chain, ok := i.(*mutatorChain); ok {
+		for _, mutator := range chain.mutators {
+			if err := blder.inject(mutator, decoder); err != nil {
+				return err
+			}
+		}
+	}
 
-		path = generatePath(blder.prefixMutate, gvk)
+	return nil
+}
+
+func (blder *Builder) getScheme() *runtime.Scheme {
+	if blder.mgr != nil {
+		return blder.mgr.GetScheme()
+	}
+
+	return blder.scheme
+}
+
+func (blder *Builder) getClient() client.Client {
+	if blder.mgr != nil {
+		return blder.mgr.GetClient()
+	}
+
+	return blder.client
+}
+
+func (blder *Builder) getEventRecorder() record.EventRecorder {
+	if blder.eventRecorder == nil && blder.mgr != nil {
+		return blder.mgr.GetEventRecorderFor(eventRecorderName)
+	}
+
+	return blder.eventRecorder
+}
+
+// getAPIReader returns the explicit uncached reader or the uncached reader of the manager. The client is not used
+// since it may be backed by a cache.
+func (blder *Builder) getAPIReader() client.Reader {
+	if blder.apiReader != nil {
+		return blder.apiReader
+	}
+	if blder.mgr != nil {
+		return blder.mgr.GetAPIReader()
+	}
+
+	return nil
+}
+
+func (blder *Builder) getRESTMapper() meta.RESTMapper {
+	if blder.mgr != nil {
+		return blder.mgr.GetRESTMapper()
+	}
+	if blder.client != nil {
+		return blder.client.RESTMapper()
+	}
+
+	return nil
+}
+
+// getLogger returns the logger of the webhook named by the kind of the api type.
+func (blder *Builder) getLogger() logr.Logger {
+	logger := log.Log
+	if blder.mgr != nil {
+		logger = blder.mgr.GetLogger()
+	}
+	logger = logger.WithName("webhook")
+
+	if kinds, err := blder.kinds(); err == nil && len(kinds) == 1 {
+		logger = logger.WithName(strings.ToLower(kinds[0].Kind)).WithValues("gvk", kinds[0].String())
 	}
-	if !isAlreadyHandled(blder.mgr, path) {
-		blder.mgr.GetWebhookServer().Register(path, w)
+
+	return logger
+}
+
+// getReader returns the cached reader of the manager or the client.
+func (blder *Builder) getReader() client.Reader {
+	if blder.mgr != nil {
+		return blder.mgr.GetCache()
+	}
+	if blder.client != nil {
+		return blder.client
 	}
 
 	return nil
 }
 
-func isAlreadyHandled(mgr ctrl.Manager, path string) bool {
-	if mgr.GetWebhookServer().WebhookMux() == nil {
+// ValidatingPath returns the path on which the validating webhook is registered.
+func (blder *Builder) ValidatingPath() (string, error) {
+	if strings.TrimSpace(blder.pathValidate) != "" {
+		return blder.pathValidate, nil
+	}
+	if blder.wildcard {
+		return "", fmt.Errorf("validating path of a webhook of all kinds must be set")
+	}
+
+	kinds, err := blder.kinds()
+	if err != nil {
+		return "", err
+	} else if len(kinds) > 1 {
+		return "", fmt.Errorf("validating path of a webhook of multiple kinds must be set")
+	}
+
+	return generatePath(blder.prefixValidate, kinds[0]), nil
+}
+
+// MutatingPath returns the path on which the mutating webhook is registered.
+func (blder *Builder) MutatingPath() (string, error) {
+	if strings.TrimSpace(blder.pathMutate) != "" {
+		return blder.pathMutate, nil
+	}
+	if blder.wildcard {
+		return "", fmt.Errorf("mutating path of a webhook of all kinds must be set")
+	}
+
+	kinds, err := blder.kinds()
+	if err != nil {
+		return "", err
+	} else if len(kinds) > 1 {
+		return "", fmt.Errorf("mutating path of a webhook of multiple kinds must be set")
+	}
+
+	return generatePath(blder.prefixMutate, kinds[0]), nil
+}
+
+// register registers the webhook on the path and returns false if the path is already handled.
+func (blder *Builder) register(path string, w *admission.Webhook) bool {
+	server := blder.webhookServer()
+	if isAlreadyHandled(server, path) {
+		return false
+	}
+
+	server.Register(path, w)
+	return true
+}
+
+func (blder *Builder) webhookServer() ctrlwebhook.Server {
+	if blder.mgr != nil {
+		return blder.mgr.GetWebhookServer()
+	}
+
+	return blder.server
+}
+
+func isAlreadyHandled(server ctrlwebhook.Server, path string) bool {
+	if server.WebhookMux() == nil {
 		return false
 	}
 
-	h, p := mgr.GetWebhookServer().WebhookMux().Handler(&http.Request{URL: &url.URL{Path: path}})
+	h, p := server.WebhookMux().Handler(&http.Request{URL: &url.URL{Path: path}})
 	if p == path && h != nil {
 		return true
 	}
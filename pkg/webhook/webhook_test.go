@@ -4,15 +4,21 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/go-logr/logr"
 	manager "github.com/snorwin/k8s-generic-webhook/pkg/mocks/manager"
 	"go.uber.org/mock/gomock"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	webhook2 "sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
 )
 
 var _ = Describe("Webhook", func() {
@@ -40,6 +46,32 @@ var _ = Describe("Webhook", func() {
 				Return(fake.NewClientBuilder().Build()).
 				AnyTimes()
 
+			mgr.EXPECT().
+				GetAPIReader().
+				Return(fake.NewClientBuilder().Build()).
+				AnyTimes()
+			mgr.EXPECT().
+				GetEventRecorderFor("generic-webhook").
+				Return(record.NewFakeRecorder(10)).
+				AnyTimes()
+			mgr.EXPECT().
+				GetRESTMapper().
+				Return(meta.NewDefaultRESTMapper(nil)).
+				AnyTimes()
+			mgr.EXPECT().
+				GetLogger().
+				Return(logr.Discard()).
+				AnyTimes()
+
+			mgr.EXPECT().
+				AddHealthzCheck(gomock.Any(), gomock.Any()).
+				Return(nil).
+				AnyTimes()
+			mgr.EXPECT().
+				AddReadyzCheck(gomock.Any(), gomock.Any()).
+				Return(nil).
+				AnyTimes()
+
 			server = &webhook2.DefaultServer{}
 			mgr.EXPECT().
 				GetWebhookServer().
@@ -70,6 +102,13 @@ var _ = Describe("Webhook", func() {
 				Complete(&webhook.ValidatingWebhook{})
 			Ω(err).ShouldNot(HaveOccurred())
 		})
+		It("should build mutating webhook with patch provenance", func() {
+			err := webhook.NewGenericWebhookManagedBy(mgr).
+				For(&corev1.Pod{}).
+				WithPatchProvenance("example.com/provenance").
+				Complete(&webhook.MutatingWebhook{})
+			Ω(err).ShouldNot(HaveOccurred())
+		})
 		It("should inject client and decoder", func() {
 			wh := &webhook.ValidatingWebhook{}
 			err := webhook.NewGenericWebhookManagedBy(mgr).
@@ -80,6 +119,31 @@ var _ = Describe("Webhook", func() {
 			Ω(wh.Client).ShouldNot(BeNil())
 			Ω(wh.Decoder).ShouldNot(BeNil())
 		})
+		It("should inject event recorder, api reader, scheme, rest mapper and logger", func() {
+			wh := &webhook.MutatingWebhook{}
+			err := webhook.NewGenericWebhookManagedBy(mgr).
+				For(&corev1.Pod{}).
+				Complete(wh)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(wh.EventRecorder).ShouldNot(BeNil())
+			Ω(wh.APIReader).ShouldNot(BeNil())
+			Ω(wh.Scheme).Should(BeIdenticalTo(mgr.GetScheme()))
+			Ω(wh.RESTMapper).ShouldNot(BeNil())
+			Ω(wh.Logger.GetSink()).Should(BeNil())
+		})
+		It("should inject client and decoder into mutator chain", func() {
+			first, second := &webhook.MutatingWebhook{}, &webhook.MutateFunc{}
+			err := webhook.NewGenericWebhookManagedBy(mgr).
+				For(&corev1.Pod{}).
+				Complete(webhook.MutatorChain(first, second))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(first.Client).ShouldNot(BeNil())
+			Ω(first.Decoder).ShouldNot(BeNil())
+			Ω(second.Client).ShouldNot(BeNil())
+			Ω(second.Decoder).ShouldNot(BeNil())
+		})
 		It("should not fail if mutating webhook is already registered", func() {
 			err := webhook.NewGenericWebhookManagedBy(mgr).
 				For(&corev1.Pod{}).
@@ -149,4 +213,130 @@ var _ = Describe("Webhook", func() {
 			Ω(err).Should(HaveOccurred())
 		})
 	})
+	Context("Build", func() {
+		var (
+			scheme *runtime.Scheme
+		)
+		BeforeEach(func() {
+			scheme = runtime.NewScheme()
+			err := corev1.AddToScheme(scheme)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		It("should build validating handler", func() {
+			validating, mutating, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				Build(&webhook.ValidatingWebhook{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(validating).ShouldNot(BeNil())
+			Ω(mutating).Should(BeNil())
+		})
+		It("should build mutating handler and inject client", func() {
+			wh := &webhook.MutatingWebhook{}
+			validating, mutating, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				WithClient(fake.NewClientBuilder().Build()).
+				Build(wh)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(validating).Should(BeNil())
+			Ω(mutating).ShouldNot(BeNil())
+			Ω(wh.Client).ShouldNot(BeNil())
+			Ω(wh.Decoder).ShouldNot(BeNil())
+			Ω(wh.APIReader).Should(BeNil())
+			Ω(wh.RESTMapper).ShouldNot(BeNil())
+			Ω(wh.Scheme).Should(BeIdenticalTo(scheme))
+			Ω(wh.EventRecorder).Should(BeNil())
+		})
+		It("should inject API reader", func() {
+			reader := fake.NewClientBuilder().Build()
+			wh := &webhook.MutatingWebhook{}
+			_, _, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				WithClient(fake.NewClientBuilder().Build()).
+				WithAPIReader(reader).
+				Build(wh)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(wh.APIReader).Should(BeIdenticalTo(reader))
+		})
+		It("should inject event recorder", func() {
+			wh := &webhook.ValidatingWebhook{}
+			recorder := record.NewFakeRecorder(10)
+			_, _, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				WithEventRecorder(recorder).
+				Build(wh)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(wh.EventRecorder).Should(BeIdenticalTo(recorder))
+			Ω(wh.Logger.GetSink()).ShouldNot(BeNil())
+		})
+		It("should handle kind of api type", func() {
+			blder := webhook.NewGenericWebhook(scheme).For(&corev1.Pod{})
+			Ω(blder.Handles(corev1.SchemeGroupVersion.WithKind("Pod"))).Should(BeTrue())
+			Ω(blder.Handles(corev1.SchemeGroupVersion.WithKind("ConfigMap"))).Should(BeFalse())
+			Ω(webhook.NewGenericWebhook(scheme).Handles(corev1.SchemeGroupVersion.WithKind("Pod"))).Should(BeFalse())
+		})
+		It("should allow unlisted operations without invoking the webhook", func() {
+			validating, _, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				WithOperations(admissionv1.Create, admissionv1.Update).
+				Build(&webhook.CELValidator{Rules: []webhook.CELRule{{Expression: "false"}}})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			pod := &corev1.Pod{}
+			resp, err := webhooktest.Create(pod).Handle(validating)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeDenied())
+
+			resp, err = webhooktest.Delete(pod).Handle(validating)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		})
+		It("should fail with unsupported operations", func() {
+			_, _, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				WithOperations(admissionv1.Connect).
+				Build(&webhook.ValidatingWebhook{})
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should return rules of all operations", func() {
+			rules, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				Rules()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rules).Should(Equal([]admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update, admissionregistrationv1.Delete},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{""},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"pods"},
+				},
+			}}))
+		})
+		It("should return rules of the listed operations", func() {
+			rules, err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				WithClient(fake.NewClientBuilder().WithScheme(scheme).Build()).
+				WithOperations(admissionv1.Update).
+				Rules()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rules).Should(HaveLen(1))
+			Ω(rules[0].Operations).Should(Equal([]admissionregistrationv1.OperationType{admissionregistrationv1.Update}))
+			Ω(rules[0].Resources).Should(Equal([]string{"pods"}))
+		})
+		It("should fail to return rules without api type", func() {
+			_, err := webhook.NewGenericWebhook(scheme).Rules()
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should fail without scheme", func() {
+			_, _, err := webhook.NewGenericWebhook(nil).
+				For(&corev1.Pod{}).
+				Build(&webhook.ValidatingWebhook{})
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should fail to complete without manager", func() {
+			err := webhook.NewGenericWebhook(scheme).
+				For(&corev1.Pod{}).
+				Complete(&webhook.ValidatingWebhook{})
+			Ω(err).Should(HaveOccurred())
+		})
+	})
 })
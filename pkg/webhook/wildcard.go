@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultExclusions are the kinds which are never handled by a wildcard webhook, since a failing or slow webhook on
+// these kinds impairs the stability of the cluster, e.g. leader election, node heartbeats, authentication,
+// scheduling or node drains.
+var defaultExclusions = []schema.GroupKind{
+	{Group: "coordination.k8s.io", Kind: "Lease"},
+	{Group: "", Kind: "Event"},
+	{Group: "events.k8s.io", Kind: "Event"},
+	{Group: "authentication.k8s.io", Kind: "TokenReview"},
+	{Group: "authentication.k8s.io", Kind: "TokenRequest"},
+	{Group: "", Kind: "Binding"},
+	{Group: "policy", Kind: "Eviction"},
+}
+
+// wildcardRule is the rule of the resources of a wildcard webhook, i.e. all resources respectively subresources.
+const wildcardRule = "*"
+
+// exclusionSet returns the set of the default exclusions and the given kinds.
+func exclusionSet(kinds []schema.GroupKind) map[schema.GroupKind]bool {
+	set := map[schema.GroupKind]bool{}
+	for _, kind := range append(append([]schema.GroupKind{}, defaultExclusions...), kinds...) {
+		set[kind] = true
+	}
+
+	return set
+}
@@ -0,0 +1,174 @@
+package webhook_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Wildcard", func() {
+	var (
+		validator *webhook.ValidateFuncs
+		kinds     []string
+	)
+	BeforeEach(func() {
+		kinds = nil
+		validator = &webhook.ValidateFuncs{
+			CreateFunc: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+				metadata := obj.(*metav1.PartialObjectMetadata)
+				kinds = append(kinds, metadata.Kind)
+				if metadata.Labels["owner"] == "" {
+					return admission.Denied("label owner is required")
+				}
+				return admission.Allowed("")
+			},
+		}
+	})
+	build := func(blder *webhook.Builder) admission.Handler {
+		validating, _, err := blder.
+			WithValidatePath("/validate-owner").
+			Build(validator)
+		Ω(err).ShouldNot(HaveOccurred())
+		return validating
+	}
+	It("should handle objects of all kinds", func() {
+		validating := build(webhook.NewGenericWebhook(scheme.Scheme).ForAll())
+
+		resp, err := webhooktest.Create(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeDenied())
+
+		resp, err = webhooktest.Create(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:   "foo",
+			Labels: map[string]string{"owner": "bar"},
+		}}).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp).Should(webhooktest.BeAllowed())
+
+		Ω(kinds).Should(Equal([]string{"ConfigMap", "Deployment"}))
+	})
+	It("should allow excluded kinds without invoking the webhook", func() {
+		validating := build(webhook.NewGenericWebhook(scheme.Scheme).
+			ForAll().
+			WithExclusions(schema.GroupKind{Kind: "ConfigMap"}))
+
+		for _, obj := range []runtime.Object{
+			&coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			&corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+		} {
+			resp, err := webhooktest.Create(obj).Handle(validating)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+		}
+		Ω(kinds).Should(BeEmpty())
+	})
+	It("should handle all kinds except the excluded ones", func() {
+		blder := webhook.NewGenericWebhook(scheme.Scheme).ForAll()
+		Ω(blder.Handles(corev1.SchemeGroupVersion.WithKind("Pod"))).Should(BeTrue())
+		Ω(blder.Handles(coordinationv1.SchemeGroupVersion.WithKind("Lease"))).Should(BeFalse())
+		Ω(blder.Handles(schema.GroupVersionKind{Group: "authentication.k8s.io", Version: "v1", Kind: "TokenReview"})).Should(BeFalse())
+		Ω(blder.Handles(schema.GroupVersionKind{Group: "authentication.k8s.io", Version: "v1", Kind: "TokenRequest"})).Should(BeFalse())
+		Ω(blder.Handles(corev1.SchemeGroupVersion.WithKind("Binding"))).Should(BeFalse())
+		Ω(blder.Handles(schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"})).Should(BeFalse())
+	})
+	It("should allow subresources without invoking the webhook unless they are handled", func() {
+		req, err := webhooktest.Create(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}).Build()
+		Ω(err).ShouldNot(HaveOccurred())
+		req.SubResource = "status"
+
+		resp := build(webhook.NewGenericWebhook(scheme.Scheme).ForAll()).Handle(context.TODO(), req)
+		Ω(resp).Should(webhooktest.BeAllowed())
+		Ω(kinds).Should(BeEmpty())
+
+		resp = build(webhook.NewGenericWebhook(scheme.Scheme).ForAll().WithSubresources()).Handle(context.TODO(), req)
+		Ω(resp).Should(webhooktest.BeDenied())
+		Ω(kinds).Should(Equal([]string{"ConfigMap"}))
+	})
+	It("should return wildcard rules of namespaced resources", func() {
+		rules, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForAll().
+			WithOperations(admissionv1.Create).
+			Rules()
+		Ω(err).ShouldNot(HaveOccurred())
+		scope := admissionregistrationv1.NamespacedScope
+		Ω(rules).Should(Equal([]admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"*"},
+				APIVersions: []string{"*"},
+				Resources:   []string{"*"},
+				Scope:       &scope,
+			},
+		}}))
+	})
+	It("should return wildcard rules of the scope and the subresources", func() {
+		rules, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForAll().
+			WithSubresources().
+			WithScope(admissionregistrationv1.AllScopes).
+			WithOperations(admissionv1.Create).
+			Rules()
+		Ω(err).ShouldNot(HaveOccurred())
+		scope := admissionregistrationv1.AllScopes
+		Ω(rules).Should(Equal([]admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"*"},
+				APIVersions: []string{"*"},
+				Resources:   []string{"*", "*/*"},
+				Scope:       &scope,
+			},
+		}}))
+	})
+	It("should return match conditions of the excluded kinds", func() {
+		conditions, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForAll().
+			WithExclusions(schema.GroupKind{Group: "apps", Kind: "ControllerRevision"}, schema.GroupKind{Kind: "Binding"}).
+			MatchConditions()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(conditions).Should(HaveLen(8))
+		Ω(conditions).Should(ContainElements(
+			admissionregistrationv1.MatchCondition{
+				Name:       "exclude-lease.coordination.k8s.io",
+				Expression: `!(request.kind.group == "coordination.k8s.io" && request.kind.kind == "Lease")`,
+			},
+			admissionregistrationv1.MatchCondition{
+				Name:       "exclude-event",
+				Expression: `!(request.kind.group == "" && request.kind.kind == "Event")`,
+			},
+			admissionregistrationv1.MatchCondition{
+				Name:       "exclude-controllerrevision.apps",
+				Expression: `!(request.kind.group == "apps" && request.kind.kind == "ControllerRevision")`,
+			},
+		))
+	})
+	It("should not return match conditions of specific kinds", func() {
+		conditions, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			MatchConditions()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(conditions).Should(BeEmpty())
+	})
+	It("should require the path", func() {
+		_, _, err := webhook.NewGenericWebhook(scheme.Scheme).
+			ForAll().
+			Build(validator)
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,407 @@
+// Package webhookeval evaluates generic webhooks offline against manifests, e.g. in CI before anything hits a cluster.
+package webhookeval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+// Exit codes returned by Main.
+const (
+	ExitAllowed = 0
+	ExitDenied  = 1
+	ExitError   = 2
+	ExitChanged = 3
+)
+
+// Evaluator runs manifests through a registered set of webhooks.
+type Evaluator struct {
+	webhooks []registration
+}
+
+type registration struct {
+	blder          *webhook.Builder
+	validating     admission.Handler
+	validatingPath string
+	mutating       admission.Handler
+	mutatingPath   string
+}
+
+// Manifest is an object read from a manifest file.
+type Manifest struct {
+	// File is the path of the manifest file.
+	File string
+	// Raw is the JSON representation of the object.
+	Raw []byte
+	// GroupVersionKind is the kind of the object.
+	GroupVersionKind schema.GroupVersionKind
+	// Namespace is the namespace of the object.
+	Namespace string
+	// Name is the name of the object.
+	Name string
+}
+
+// Result is the result of the evaluation of a manifest.
+type Result struct {
+	Manifest
+
+	// Operation is the operation of the synthesized AdmissionRequest.
+	Operation admissionv1.Operation
+	// Evaluated is false if no registered webhook handles the kind of the manifest.
+	Evaluated bool
+	// Allowed indicates whether the request was allowed by all webhooks.
+	Allowed bool
+	// Message is the message of the denial.
+	Message string
+	// Warnings are the warnings of all webhooks.
+	Warnings []string
+	// Mutated is the JSON representation of the mutated object, nil if no webhook changed the object.
+	Mutated []byte
+}
+
+// New returns a new Evaluator.
+func New() *Evaluator {
+	return &Evaluator{}
+}
+
+// Register builds the webhook with the Builder and registers it for the evaluation.
+// The Builder should be created by webhook.NewGenericWebhook.
+func (e *Evaluator) Register(blder *webhook.Builder, i interface{}) error {
+	validating, mutating, err := blder.Build(i)
+	if err != nil {
+		return err
+	}
+
+	w := registration{blder: blder, validating: validating, mutating: mutating}
+	if validating != nil {
+		if w.validatingPath, err = blder.ValidatingPath(); err != nil {
+			return err
+		}
+	}
+	if mutating != nil {
+		if w.mutatingPath, err = blder.MutatingPath(); err != nil {
+			return err
+		}
+	}
+
+	e.webhooks = append(e.webhooks, w)
+	return nil
+}
+
+// Main parses the command line arguments, evaluates the manifests and prints the results. It returns the exit code
+// of the command which is non-zero if a request is denied or if the evaluation fails.
+func (e *Evaluator) Main(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("webhook-eval", flag.ContinueOnError)
+	flags.SetOutput(out)
+	baseline := flags.String("baseline", "", "file or directory with the baseline manifests, matching manifests are evaluated as UPDATE")
+	replay := flags.Bool("replay", false, "replay the recorded requests of the paths and compare the responses")
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintf(out, "usage: webhook-eval [-baseline <path>] [-replay] <path>...\n")
+		return ExitError
+	}
+
+	if *replay {
+		return e.replay(flags.Args(), out)
+	}
+
+	var baselines []Manifest
+	if *baseline != "" {
+		var err error
+		if baselines, err = ReadManifests(*baseline); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return ExitError
+		}
+	}
+
+	var manifests []Manifest
+	for _, path := range flags.Args() {
+		read, err := ReadManifests(path)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return ExitError
+		}
+		manifests = append(manifests, read...)
+	}
+
+	results, err := e.Evaluate(context.Background(), manifests, baselines)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return ExitError
+	}
+
+	code := ExitAllowed
+	for _, result := range results {
+		if err := PrintResult(out, result); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return ExitError
+		}
+		if !result.Allowed {
+			code = ExitDenied
+		}
+	}
+
+	return code
+}
+
+// replay replays the recordings of the paths, prints the results and returns the exit code of the command which is
+// non-zero if a response changed.
+func (e *Evaluator) replay(paths []string, out io.Writer) int {
+	var recordings []RecordedRequest
+	for _, path := range paths {
+		read, err := ReadRecordings(path)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return ExitError
+		}
+		recordings = append(recordings, read...)
+	}
+
+	results, err := e.Replay(context.Background(), recordings)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return ExitError
+	}
+
+	code := ExitAllowed
+	for _, result := range results {
+		if err := PrintReplayResult(out, result); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return ExitError
+		}
+		if len(result.Diffs) > 0 {
+			code = ExitChanged
+		}
+	}
+
+	return code
+}
+
+// Evaluate synthesizes a CREATE AdmissionRequest for each manifest, or an UPDATE request if a baseline with the same
+// kind, namespace and name exists, and runs it through the mutating and then the validating webhooks.
+func (e *Evaluator) Evaluate(ctx context.Context, manifests []Manifest, baselines []Manifest) ([]Result, error) {
+	results := make([]Result, 0, len(manifests))
+	for _, manifest := range manifests {
+		result, err := e.evaluate(ctx, manifest, findBaseline(manifest, baselines))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", manifest.File, err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, manifest Manifest, baseline *Manifest) (Result, error) {
+	gvr, _ := meta.UnsafeGuessKindToResource(manifest.GroupVersionKind)
+	req := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       uuid.NewUUID(),
+			Kind:      metav1.GroupVersionKind(manifest.GroupVersionKind),
+			Resource:  metav1.GroupVersionResource(gvr),
+			Name:      manifest.Name,
+			Namespace: manifest.Namespace,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: manifest.Raw},
+		},
+	}
+	if baseline != nil {
+		req.Operation = admissionv1.Update
+		req.OldObject = runtime.RawExtension{Raw: baseline.Raw}
+	}
+
+	result := Result{
+		Manifest:  manifest,
+		Operation: req.Operation,
+		Allowed:   true,
+	}
+
+	var handlers []admission.Handler
+	for _, w := range e.webhooks {
+		if w.mutating != nil && w.blder.Handles(manifest.GroupVersionKind) {
+			handlers = append(handlers, w.mutating)
+		}
+	}
+	for _, w := range e.webhooks {
+		if w.validating != nil && w.blder.Handles(manifest.GroupVersionKind) {
+			handlers = append(handlers, w.validating)
+		}
+	}
+
+	for _, h := range handlers {
+		result.Evaluated = true
+
+		resp := h.Handle(ctx, req)
+		result.Warnings = append(result.Warnings, resp.Warnings...)
+		if !resp.Allowed {
+			result.Allowed = false
+			if resp.Result != nil {
+				result.Message = resp.Result.Message
+			}
+			return result, nil
+		}
+
+		if len(resp.Patches) > 0 {
+			patched, err := webhook.ApplyJSONPatch(req.Object.Raw, resp.Patches)
+			if err != nil {
+				return result, err
+			}
+			req.Object.Raw = patched
+			result.Mutated = req.Object.Raw
+		}
+	}
+
+	return result, nil
+}
+
+// PrintResult prints the verdict, the warnings and the mutated object of the result.
+func PrintResult(out io.Writer, result Result) error {
+	verdict := "ALLOWED"
+	if !result.Evaluated {
+		verdict = "SKIPPED"
+	} else if !result.Allowed {
+		verdict = "DENIED"
+	}
+
+	name := result.Name
+	if result.Namespace != "" {
+		name = result.Namespace + "/" + name
+	}
+
+	line := fmt.Sprintf("%s: %s %s %s %s", result.File, verdict, result.Operation, result.GroupVersionKind.Kind, name)
+	if result.Message != "" {
+		line += ": " + result.Message
+	}
+	if _, err := fmt.Fprintln(out, line); err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		if _, err := fmt.Fprintf(out, "  warning: %s\n", warning); err != nil {
+			return err
+		}
+	}
+
+	if result.Mutated != nil {
+		mutated, err := yaml.JSONToYAML(result.Mutated)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", mutated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadManifests reads all objects of the YAML or JSON manifest file or of all manifest files in the directory.
+func ReadManifests(path string) ([]Manifest, error) {
+	var manifests []Manifest
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (file != path && !isManifestFile(file)) {
+			return nil
+		}
+
+		read, err := readManifestFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		manifests = append(manifests, read...)
+		return nil
+	})
+
+	return manifests, err
+}
+
+func isManifestFile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func readManifestFile(file string) ([]Manifest, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []Manifest
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(raw)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return manifests, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &obj.Object); err != nil {
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("manifest must specify apiVersion and kind")
+		}
+
+		marshalled, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		manifests = append(manifests, Manifest{
+			File:             file,
+			Raw:              marshalled,
+			GroupVersionKind: obj.GroupVersionKind(),
+			Namespace:        obj.GetNamespace(),
+			Name:             obj.GetName(),
+		})
+	}
+}
+
+func findBaseline(manifest Manifest, baselines []Manifest) *Manifest {
+	for idx := range baselines {
+		if baselines[idx].GroupVersionKind == manifest.GroupVersionKind &&
+			baselines[idx].Namespace == manifest.Namespace &&
+			baselines[idx].Name == manifest.Name {
+			return &baselines[idx]
+		}
+	}
+
+	return nil
+}
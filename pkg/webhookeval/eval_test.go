@@ -0,0 +1,86 @@
+package webhookeval_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/internal/fixtures"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhookeval"
+)
+
+var _ = Describe("Evaluator", func() {
+	var (
+		evaluator *webhookeval.Evaluator
+	)
+	BeforeEach(func() {
+		evaluator = webhookeval.New()
+		err := evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &fixtures.OwnerWebhook{})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+	It("should fail to register invalid webhook", func() {
+		err := evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme), &fixtures.OwnerWebhook{})
+		Ω(err).Should(HaveOccurred())
+	})
+	It("should read manifests", func() {
+		manifests, err := webhookeval.ReadManifests(filepath.Join("testdata", "manifests"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifests).Should(HaveLen(3))
+		Ω(manifests[0].GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+		Ω(manifests[1].Name).Should(Equal("foo"))
+		Ω(manifests[2].Name).Should(Equal("baz"))
+	})
+	It("should evaluate manifests", func() {
+		manifests, err := webhookeval.ReadManifests(filepath.Join("testdata", "manifests"))
+		Ω(err).ShouldNot(HaveOccurred())
+		baselines, err := webhookeval.ReadManifests(filepath.Join("testdata", "baseline"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		results, err := evaluator.Evaluate(context.TODO(), manifests, baselines)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(results).Should(HaveLen(3))
+
+		Ω(results[0].Evaluated).Should(BeFalse())
+		Ω(results[0].Allowed).Should(BeTrue())
+
+		Ω(results[1].Evaluated).Should(BeTrue())
+		Ω(results[1].Operation).Should(Equal(admissionv1.Update))
+		Ω(results[1].Allowed).Should(BeTrue())
+		Ω(results[1].Warnings).Should(ConsistOf("node name defaulted"))
+		Ω(string(results[1].Mutated)).Should(ContainSubstring(`"nodeName":"jin"`))
+
+		Ω(results[2].Evaluated).Should(BeTrue())
+		Ω(results[2].Operation).Should(Equal(admissionv1.Create))
+		Ω(results[2].Allowed).Should(BeFalse())
+		Ω(results[2].Message).Should(Equal("owner label is required"))
+	})
+	It("should print results and exit non-zero on denials", func() {
+		out := &bytes.Buffer{}
+		code := evaluator.Main([]string{"-baseline", filepath.Join("testdata", "baseline"), filepath.Join("testdata", "manifests")}, out)
+		Ω(code).Should(Equal(webhookeval.ExitDenied))
+		Ω(out.String()).Should(ContainSubstring("SKIPPED CREATE ConfigMap bar/foo"))
+		Ω(out.String()).Should(ContainSubstring("ALLOWED UPDATE Pod bar/foo"))
+		Ω(out.String()).Should(ContainSubstring("  warning: node name defaulted"))
+		Ω(out.String()).Should(ContainSubstring("nodeName: jin"))
+		Ω(out.String()).Should(ContainSubstring("DENIED CREATE Pod bar/baz: owner label is required"))
+	})
+	It("should exit zero if all requests are allowed", func() {
+		out := &bytes.Buffer{}
+		code := evaluator.Main([]string{filepath.Join("testdata", "manifests", "configmap.json")}, out)
+		Ω(code).Should(Equal(webhookeval.ExitAllowed))
+	})
+	It("should fail with invalid arguments", func() {
+		out := &bytes.Buffer{}
+		Ω(evaluator.Main([]string{}, out)).Should(Equal(webhookeval.ExitError))
+		Ω(evaluator.Main([]string{"-foo"}, out)).Should(Equal(webhookeval.ExitError))
+		Ω(evaluator.Main([]string{filepath.Join("testdata", "missing")}, out)).Should(Equal(webhookeval.ExitError))
+	})
+})
@@ -0,0 +1,230 @@
+package webhookeval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+// maxRecordingSize is the maximum size of a single recorded line.
+const maxRecordingSize = 64 * 1024 * 1024
+
+// RecordedRequest is a recording read from a recording file.
+type RecordedRequest struct {
+	webhook.Recording
+
+	// File is the path of the recording file.
+	File string
+	// Line is the line of the recording in the file.
+	Line int
+}
+
+// ReplayResult is the result of the replay of a recorded request.
+type ReplayResult struct {
+	RecordedRequest
+
+	// Replayed is false if no registered webhook of the same type and path handles the kind of the request.
+	Replayed bool
+	// Allowed indicates whether the replayed request was allowed.
+	Allowed bool
+	// Message is the message of the replayed response.
+	Message string
+	// Patches are the JSON patch operations of the replayed response.
+	Patches []jsonpatch.JsonPatchOperation
+	// Diffs are the differences between the recorded and the replayed verdict and patches.
+	Diffs []string
+}
+
+// ReadRecordings reads all recordings of the recording file or of all '*.jsonl' files in the directory.
+func ReadRecordings(path string) ([]RecordedRequest, error) {
+	var recordings []RecordedRequest
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (file != path && filepath.Ext(file) != ".jsonl") {
+			return nil
+		}
+
+		read, err := readRecordingFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		recordings = append(recordings, read...)
+		return nil
+	})
+
+	return recordings, err
+}
+
+func readRecordingFile(file string) ([]RecordedRequest, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recordings []RecordedRequest
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxRecordingSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		recording := RecordedRequest{File: file, Line: line}
+		if err := json.Unmarshal(scanner.Bytes(), &recording.Recording); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		recordings = append(recordings, recording)
+	}
+
+	return recordings, scanner.Err()
+}
+
+// Replay feeds the recorded requests into the registered webhook of the same type and path which handles the kind of
+// the request and compares the verdicts and the patches with the recorded responses. The replayed responses are
+// redacted like the recorded ones before they are compared.
+func (e *Evaluator) Replay(ctx context.Context, recordings []RecordedRequest) ([]ReplayResult, error) {
+	results := make([]ReplayResult, 0, len(recordings))
+	for _, recording := range recordings {
+		result := ReplayResult{RecordedRequest: recording}
+
+		h := e.handlerFor(recording.Type, recording.Webhook, schema.GroupVersionKind(recording.Request.Kind))
+		if h != nil {
+			resp := h.Handle(ctx, admission.Request{AdmissionRequest: recording.Request})
+
+			replayed := webhook.Recording{Request: recording.Request, Response: resp.AdmissionResponse, Patches: resp.Patches}
+			if err := replayed.Redact(recording.Redacted); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", recording.File, recording.Line, err)
+			}
+
+			result.Replayed = true
+			result.Allowed = replayed.Response.Allowed
+			result.Patches = replayed.Patches
+			if replayed.Response.Result != nil {
+				result.Message = replayed.Response.Result.Message
+			}
+
+			var err error
+			if result.Diffs, err = diff(recording.Recording, result); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", recording.File, recording.Line, err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// PrintReplayResult prints the differences between the recorded and the replayed response.
+func PrintReplayResult(out io.Writer, result ReplayResult) error {
+	verdict := "UNCHANGED"
+	if !result.Replayed {
+		verdict = "SKIPPED"
+	} else if len(result.Diffs) > 0 {
+		verdict = "CHANGED"
+	}
+
+	name := result.Request.Name
+	if result.Request.Namespace != "" {
+		name = result.Request.Namespace + "/" + name
+	}
+
+	if _, err := fmt.Fprintf(out, "%s:%d: %s %s %s %s %s\n", result.File, result.Line, verdict,
+		result.Webhook, result.Request.Operation, result.Request.Kind.Kind, name); err != nil {
+		return err
+	}
+
+	for _, d := range result.Diffs {
+		if _, err := fmt.Fprintf(out, "  %s\n", d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handlerFor returns the handler of the registered webhook of the type and the path which handles the kind.
+func (e *Evaluator) handlerFor(typ webhook.WebhookType, path string, gvk schema.GroupVersionKind) admission.Handler {
+	for _, w := range e.webhooks {
+		if !w.blder.Handles(gvk) {
+			continue
+		}
+
+		if typ == webhook.Validating && w.validating != nil && w.validatingPath == path {
+			return w.validating
+		} else if typ == webhook.Mutating && w.mutating != nil && w.mutatingPath == path {
+			return w.mutating
+		}
+	}
+
+	return nil
+}
+
+func diff(recording webhook.Recording, result ReplayResult) ([]string, error) {
+	var diffs []string
+	if recording.Response.Allowed != result.Allowed {
+		diffs = append(diffs, fmt.Sprintf("allowed: %t -> %t", recording.Response.Allowed, result.Allowed))
+	}
+
+	message := ""
+	if recording.Response.Result != nil {
+		message = recording.Response.Result.Message
+	}
+	if message != result.Message {
+		diffs = append(diffs, fmt.Sprintf("message: %q -> %q", message, result.Message))
+	}
+
+	recorded, err := normalizePatches(recording.Patches)
+	if err != nil {
+		return nil, err
+	}
+	replayed, err := normalizePatches(result.Patches)
+	if err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(recorded, replayed) {
+		recordedRaw, _ := json.Marshal(recorded)
+		replayedRaw, _ := json.Marshal(replayed)
+		diffs = append(diffs, fmt.Sprintf("patches: %s -> %s", recordedRaw, replayedRaw))
+	}
+
+	return diffs, nil
+}
+
+// normalizePatches sorts the patches and converts them to their generic JSON representation.
+func normalizePatches(patches []jsonpatch.JsonPatchOperation) ([]interface{}, error) {
+	sorted := append([]jsonpatch.JsonPatchOperation{}, patches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Operation < sorted[j].Operation
+	})
+
+	raw, err := json.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized []interface{}
+	err = json.Unmarshal(raw, &normalized)
+	return normalized, err
+}
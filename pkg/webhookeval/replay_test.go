@@ -0,0 +1,144 @@
+package webhookeval_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/internal/fixtures"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhookeval"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Replay", func() {
+	var (
+		dir string
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "recordings")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{Dir: dir})
+		Ω(err).ShouldNot(HaveOccurred())
+		defer recorder.Close()
+
+		validating, mutating, err := webhook.NewGenericWebhook(scheme.Scheme).
+			For(&corev1.Pod{}).
+			WithRecorder(recorder).
+			Build(&fixtures.OwnerWebhook{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		for _, pod := range []*corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{"owner": "alice"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "bar"}},
+		} {
+			_, err = webhooktest.Create(pod).Handle(mutating)
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = webhooktest.Create(pod).Handle(validating)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		_, err = webhooktest.Create(&corev1.ConfigMap{}).Handle(validating)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		Ω(os.RemoveAll(dir)).Should(Succeed())
+	})
+	It("should replay recordings without changes", func() {
+		recordings, err := webhookeval.ReadRecordings(dir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recordings).Should(HaveLen(5))
+
+		evaluator := webhookeval.New()
+		Ω(evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &fixtures.OwnerWebhook{})).Should(Succeed())
+
+		results, err := evaluator.Replay(context.TODO(), recordings)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(results).Should(HaveLen(5))
+		for _, result := range results[:4] {
+			Ω(result.Replayed).Should(BeTrue())
+			Ω(result.Diffs).Should(BeEmpty())
+		}
+		Ω(results[4].Replayed).Should(BeFalse())
+
+		out := &bytes.Buffer{}
+		Ω(evaluator.Main([]string{"-replay", dir}, out)).Should(Equal(webhookeval.ExitAllowed))
+		Ω(out.String()).Should(ContainSubstring("UNCHANGED /mutate--v1-pod CREATE Pod foo"))
+		Ω(out.String()).Should(ContainSubstring("SKIPPED /validate--v1-pod CREATE ConfigMap"))
+	})
+	It("should report changed verdicts and patches", func() {
+		evaluator := webhookeval.New()
+		Ω(evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &webhook.ValidateFuncs{})).Should(Succeed())
+		Ω(evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &webhook.MutateFunc{
+			Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+				obj.(*corev1.Pod).Spec.NodeName = "yang"
+				return admission.Allowed("")
+			},
+		})).Should(Succeed())
+
+		out := &bytes.Buffer{}
+		Ω(evaluator.Main([]string{"-replay", dir}, out)).Should(Equal(webhookeval.ExitChanged))
+		Ω(out.String()).Should(ContainSubstring("CHANGED /mutate--v1-pod CREATE Pod foo"))
+		Ω(out.String()).Should(ContainSubstring(`"value":"yang"`))
+		Ω(out.String()).Should(ContainSubstring("CHANGED /validate--v1-pod CREATE Pod bar"))
+		Ω(out.String()).Should(ContainSubstring("allowed: false -> true"))
+		Ω(out.String()).Should(ContainSubstring(`message: "owner label is required" -> ""`))
+	})
+	It("should redact the replayed responses like the recorded ones", func() {
+		redactedDir := filepath.Join(dir, "redacted")
+		recorder, err := webhook.NewRecorder(webhook.RecorderOptions{Dir: redactedDir, RedactFields: []string{"spec.nodeName"}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		blder := webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{})
+		_, mutating, err := blder.WithRecorder(recorder).Build(&fixtures.OwnerWebhook{})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = webhooktest.Create(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}).Handle(mutating)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recorder.Close()).Should(Succeed())
+
+		recordings, err := webhookeval.ReadRecordings(redactedDir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recordings).Should(HaveLen(1))
+		Ω(recordings[0].Patches[0].Value).Should(Equal("REDACTED"))
+
+		evaluator := webhookeval.New()
+		Ω(evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &fixtures.OwnerWebhook{})).Should(Succeed())
+		results, err := evaluator.Replay(context.TODO(), recordings)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(results).Should(HaveLen(1))
+		Ω(results[0].Replayed).Should(BeTrue())
+		Ω(results[0].Diffs).Should(BeEmpty())
+	})
+	It("should replay recordings against the webhook of the same path", func() {
+		evaluator := webhookeval.New()
+		Ω(evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}).WithValidatePath("/validate-other"),
+			&webhook.ValidateFuncs{})).Should(Succeed())
+		Ω(evaluator.Register(webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{}), &fixtures.OwnerWebhook{})).Should(Succeed())
+
+		recordings, err := webhookeval.ReadRecordings(dir)
+		Ω(err).ShouldNot(HaveOccurred())
+		results, err := evaluator.Replay(context.TODO(), recordings)
+		Ω(err).ShouldNot(HaveOccurred())
+		for _, result := range results[:4] {
+			Ω(result.Replayed).Should(BeTrue())
+			Ω(result.Diffs).Should(BeEmpty())
+		}
+	})
+	It("should fail for invalid recordings", func() {
+		Ω(os.WriteFile(dir+"/invalid.jsonl", []byte("{"), 0o600)).Should(Succeed())
+		_, err := webhookeval.ReadRecordings(dir)
+		Ω(err).Should(HaveOccurred())
+		Ω(webhookeval.New().Main([]string{"-replay", dir}, &bytes.Buffer{})).Should(Equal(webhookeval.ExitError))
+	})
+})
@@ -0,0 +1,13 @@
+package webhookeval_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhookEval(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WebhookEval Test Suite")
+}
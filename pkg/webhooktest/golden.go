@@ -0,0 +1,191 @@
+package webhooktest
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+const (
+	requestSuffix  = ".request.yaml"
+	expectedSuffix = ".expected.yaml"
+
+	// UpdateGoldenFlag is the flag which rewrites the golden files instead of comparing them, e.g. 'go test . -update'.
+	UpdateGoldenFlag = "update"
+	// UpdateGoldenEnv is the environment variable which rewrites the golden files instead of comparing them if set to
+	// true, it is used if the flag cannot be passed to the tests.
+	UpdateGoldenEnv = "UPDATE_GOLDEN"
+)
+
+func init() {
+	// the flag may already be registered by another package of the test binary
+	if flag.Lookup(UpdateGoldenFlag) == nil {
+		flag.Bool(UpdateGoldenFlag, false, "rewrite the golden files instead of comparing them")
+	}
+}
+
+// TestingT is the subset of testing.T used by the golden file runner, it is implemented by GinkgoT() as well.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// GoldenResult is the content of an expected golden file.
+type GoldenResult struct {
+	// Allowed indicates whether the request was allowed.
+	Allowed bool `json:"allowed"`
+	// Code is the status code of the response result.
+	Code int32 `json:"code,omitempty"`
+	// Message is the message of the response result.
+	Message string `json:"message,omitempty"`
+	// Warnings are the warnings of the response.
+	Warnings []string `json:"warnings,omitempty"`
+	// Patched is the object after the application of the patch, omitted if the response does not contain a patch.
+	Patched map[string]interface{} `json:"patched,omitempty"`
+}
+
+// RunGoldenFiles feeds the AdmissionReview of each '*.request.yaml' file in the directory into the webhook built
+// by the Builder and compares the result with the corresponding '*.expected.yaml' file. If the webhook is both
+// mutating and validating, the mutated object is validated like it is done by the API server.
+// The expected files are rewritten instead if the tests are run with the flag -update or if the environment variable
+// UPDATE_GOLDEN is set to true.
+func RunGoldenFiles(t TestingT, dir string, blder *webhook.Builder, i interface{}) {
+	t.Helper()
+
+	validating, mutating, err := blder.Build(i)
+	if err != nil {
+		t.Fatalf("failed to build webhook: %v", err)
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+requestSuffix))
+	if err != nil {
+		t.Fatalf("failed to list golden files: %v", err)
+		return
+	}
+	sort.Strings(files)
+
+	update := updateGoldenFiles()
+	for _, file := range files {
+		expectedFile := strings.TrimSuffix(file, requestSuffix) + expectedSuffix
+
+		actual, err := runGoldenFile(file, validating, mutating)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+
+		if update {
+			raw, err := yaml.Marshal(actual)
+			if err == nil {
+				err = os.WriteFile(expectedFile, raw, 0o644)
+			}
+			if err != nil {
+				t.Errorf("%s: failed to update golden file: %v", expectedFile, err)
+			}
+			continue
+		}
+
+		raw, err := os.ReadFile(expectedFile)
+		if err != nil {
+			t.Errorf("%s: failed to read golden file: %v", expectedFile, err)
+			continue
+		}
+		expected := GoldenResult{}
+		if err := yaml.Unmarshal(raw, &expected); err != nil {
+			t.Errorf("%s: failed to decode golden file: %v", expectedFile, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			actualRaw, _ := yaml.Marshal(actual)
+			t.Errorf("%s: result does not match golden file %s, got:\n%s", file, expectedFile, actualRaw)
+		}
+	}
+}
+
+// updateGoldenFiles returns true if the golden files are rewritten, i.e. if the flag or the environment variable is set.
+func updateGoldenFiles() bool {
+	if f := flag.Lookup(UpdateGoldenFlag); f != nil {
+		if update, err := strconv.ParseBool(f.Value.String()); err == nil && update {
+			return true
+		}
+	}
+
+	update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv))
+	return update
+}
+
+// runGoldenFile runs the AdmissionReview of the file through the mutating and the validating handler.
+func runGoldenFile(file string, validating admission.Handler, mutating admission.Handler) (GoldenResult, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return GoldenResult{}, err
+	}
+
+	review := admissionv1.AdmissionReview{}
+	if err := yaml.Unmarshal(raw, &review); err != nil {
+		return GoldenResult{}, err
+	}
+	if review.Request == nil {
+		return GoldenResult{}, fmt.Errorf("AdmissionReview does not contain a request")
+	}
+	req := admission.Request{AdmissionRequest: *review.Request}
+
+	var (
+		resp     = admission.Allowed("")
+		warnings []string
+		patched  []byte
+	)
+	if mutating != nil {
+		resp = mutating.Handle(context.TODO(), req)
+		warnings = append(warnings, resp.Warnings...)
+
+		if resp.Allowed && len(resp.Patches) > 0 {
+			if patched, err = webhook.ApplyJSONPatch(req.Object.Raw, resp.Patches); err != nil {
+				return GoldenResult{}, err
+			}
+			req.Object.Raw = patched
+		}
+	}
+	if validating != nil && resp.Allowed {
+		resp = validating.Handle(context.TODO(), req)
+		warnings = append(warnings, resp.Warnings...)
+	}
+
+	result := GoldenResult{
+		Allowed:  resp.Allowed,
+		Warnings: warnings,
+	}
+	if resp.Result != nil {
+		result.Code = resp.Result.Code
+		result.Message = resp.Result.Message
+	}
+	if patched != nil {
+		if err := json.Unmarshal(patched, &result.Patched); err != nil {
+			return GoldenResult{}, err
+		}
+	}
+
+	// normalize the result in order to compare it with the decoded golden file
+	normalized, err := yaml.Marshal(result)
+	if err != nil {
+		return GoldenResult{}, err
+	}
+	result = GoldenResult{}
+	return result, yaml.Unmarshal(normalized, &result)
+}
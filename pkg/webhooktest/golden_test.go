@@ -0,0 +1,103 @@
+package webhooktest_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/internal/fixtures"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+// recordingT records the failures of the golden file runner.
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("Golden Files", func() {
+	var (
+		blder *webhook.Builder
+	)
+	BeforeEach(func() {
+		blder = webhook.NewGenericWebhook(scheme.Scheme).For(&corev1.Pod{})
+	})
+	It("should match golden files", func() {
+		webhooktest.RunGoldenFiles(GinkgoT(), filepath.Join("testdata", "golden"), blder, &fixtures.OwnerWebhook{})
+	})
+	It("should report mismatches and missing golden files", func() {
+		if flag.Lookup(webhooktest.UpdateGoldenFlag).Value.String() == "true" || os.Getenv(webhooktest.UpdateGoldenEnv) == "true" {
+			Skip("golden files are updated")
+		}
+
+		dir, err := os.MkdirTemp("", "golden")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		for _, file := range []string{"pod-allowed.request.yaml", "pod-denied.request.yaml"} {
+			raw, err := os.ReadFile(filepath.Join("testdata", "golden", file))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(os.WriteFile(filepath.Join(dir, file), raw, 0o644)).Should(Succeed())
+		}
+		Ω(os.WriteFile(filepath.Join(dir, "pod-allowed.expected.yaml"), []byte("allowed: false\n"), 0o644)).Should(Succeed())
+
+		t := &recordingT{}
+		webhooktest.RunGoldenFiles(t, dir, blder, &fixtures.OwnerWebhook{})
+		Ω(t.errors).Should(HaveLen(2))
+		Ω(t.errors[0]).Should(ContainSubstring("does not match golden file"))
+		Ω(t.errors[1]).Should(ContainSubstring("failed to read golden file"))
+	})
+	It("should rewrite golden files if the update flag is set", func() {
+		dir, err := os.MkdirTemp("", "golden")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		raw, err := os.ReadFile(filepath.Join("testdata", "golden", "pod-denied.request.yaml"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(os.WriteFile(filepath.Join(dir, "pod-denied.request.yaml"), raw, 0o644)).Should(Succeed())
+
+		update := flag.Lookup(webhooktest.UpdateGoldenFlag).Value.String()
+		Ω(flag.Set(webhooktest.UpdateGoldenFlag, "true")).Should(Succeed())
+		defer func() { _ = flag.Set(webhooktest.UpdateGoldenFlag, update) }()
+
+		webhooktest.RunGoldenFiles(GinkgoT(), dir, blder, &fixtures.OwnerWebhook{})
+		Ω(filepath.Join(dir, "pod-denied.expected.yaml")).Should(BeAnExistingFile())
+	})
+	It("should rewrite golden files if the update environment variable is set", func() {
+		dir, err := os.MkdirTemp("", "golden")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		raw, err := os.ReadFile(filepath.Join("testdata", "golden", "pod-denied.request.yaml"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(os.WriteFile(filepath.Join(dir, "pod-denied.request.yaml"), raw, 0o644)).Should(Succeed())
+
+		Ω(os.Setenv(webhooktest.UpdateGoldenEnv, "true")).Should(Succeed())
+		defer os.Unsetenv(webhooktest.UpdateGoldenEnv)
+
+		webhooktest.RunGoldenFiles(GinkgoT(), dir, blder, &fixtures.OwnerWebhook{})
+		Ω(filepath.Join(dir, "pod-denied.expected.yaml")).Should(BeAnExistingFile())
+	})
+	It("should fail if webhook cannot be built", func() {
+		t := &recordingT{}
+		webhooktest.RunGoldenFiles(t, "testdata", webhook.NewGenericWebhook(scheme.Scheme), &fixtures.OwnerWebhook{})
+		Ω(t.errors).Should(HaveLen(1))
+	})
+})
@@ -0,0 +1,88 @@
+package webhooktest
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/onsi/gomega/gcustom"
+	"github.com/onsi/gomega/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// BeAllowed succeeds if the admission.Response allows the request.
+func BeAllowed() types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(resp admission.Response) (bool, error) {
+		return resp.Allowed, nil
+	}).WithTemplate("Expected response {{.To}} be allowed, got:\n{{.FormattedActual}}")
+}
+
+// BeDenied succeeds if the admission.Response denies the request.
+func BeDenied() types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(resp admission.Response) (bool, error) {
+		return !resp.Allowed, nil
+	}).WithTemplate("Expected response {{.To}} be denied, got:\n{{.FormattedActual}}")
+}
+
+// BeDeniedWithReason succeeds if the admission.Response denies the request and either the message or the reason of
+// the result status equals the given reason.
+func BeDeniedWithReason(reason string) types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(resp admission.Response) (bool, error) {
+		if resp.Allowed || resp.Result == nil {
+			return false, nil
+		}
+
+		return resp.Result.Message == reason || string(resp.Result.Reason) == reason, nil
+	}).WithTemplate("Expected response {{.To}} be denied with reason {{printf \"%q\" .Data}}, got:\n{{.FormattedActual}}", reason)
+}
+
+// HaveWarning succeeds if the admission.Response contains the given warning.
+func HaveWarning(warning string) types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(resp admission.Response) (bool, error) {
+		for _, w := range resp.Warnings {
+			if w == warning {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}).WithTemplate("Expected response {{.To}} have warning {{printf \"%q\" .Data}}, got:\n{{.FormattedActual}}", warning)
+}
+
+// HavePatch succeeds if the admission.Response contains a JSON patch operation with the given operation, path and
+// value. The values are compared by their JSON representation.
+func HavePatch(operation string, path string, value interface{}) types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(resp admission.Response) (bool, error) {
+		expected, err := normalize(value)
+		if err != nil {
+			return false, err
+		}
+
+		for _, patch := range resp.Patches {
+			if patch.Operation != operation || patch.Path != path {
+				continue
+			}
+
+			actual, err := normalize(patch.Value)
+			if err != nil {
+				return false, err
+			}
+			if reflect.DeepEqual(actual, expected) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}).WithTemplate("Expected response {{.To}} have patch {{printf \"%v\" .Data}}, got:\n{{.FormattedActual}}", []interface{}{operation, path, value})
+}
+
+// normalize converts the value to its generic JSON representation.
+func normalize(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	err = json.Unmarshal(raw, &normalized)
+	return normalized, err
+}
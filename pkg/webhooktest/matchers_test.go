@@ -0,0 +1,48 @@
+package webhooktest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gomodules.xyz/jsonpatch/v2"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Matchers", func() {
+	It("should match allowed", func() {
+		Ω(admission.Allowed("")).Should(webhooktest.BeAllowed())
+		Ω(admission.Denied("")).ShouldNot(webhooktest.BeAllowed())
+	})
+	It("should match denied", func() {
+		Ω(admission.Denied("")).Should(webhooktest.BeDenied())
+		Ω(admission.Allowed("")).ShouldNot(webhooktest.BeDenied())
+	})
+	It("should match denied with reason", func() {
+		Ω(admission.Denied("foo")).Should(webhooktest.BeDeniedWithReason("foo"))
+		Ω(admission.Denied("foo")).Should(webhooktest.BeDeniedWithReason("Forbidden"))
+		Ω(admission.Denied("foo")).ShouldNot(webhooktest.BeDeniedWithReason("bar"))
+		Ω(admission.Allowed("foo")).ShouldNot(webhooktest.BeDeniedWithReason("foo"))
+	})
+	It("should match warnings", func() {
+		Ω(admission.Allowed("").WithWarnings("foo")).Should(webhooktest.HaveWarning("foo"))
+		Ω(admission.Allowed("")).ShouldNot(webhooktest.HaveWarning("foo"))
+	})
+	It("should match patches", func() {
+		resp := admission.Patched("",
+			jsonpatch.NewOperation("replace", "/metadata/name", "bar"),
+			jsonpatch.NewOperation("add", "/spec/replicas", float64(3)),
+			jsonpatch.NewOperation("remove", "/spec/nodeName", nil),
+		)
+		Ω(resp).Should(webhooktest.HavePatch("replace", "/metadata/name", "bar"))
+		Ω(resp).Should(webhooktest.HavePatch("add", "/spec/replicas", 3))
+		Ω(resp).Should(webhooktest.HavePatch("remove", "/spec/nodeName", nil))
+		Ω(resp).ShouldNot(webhooktest.HavePatch("replace", "/metadata/name", "foo"))
+		Ω(resp).ShouldNot(webhooktest.HavePatch("add", "/metadata/name", "bar"))
+	})
+	It("should fail for other types", func() {
+		_, err := webhooktest.BeAllowed().Match("foo")
+		Ω(err).Should(HaveOccurred())
+	})
+})
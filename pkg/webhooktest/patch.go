@@ -0,0 +1,48 @@
+package webhooktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+// ApplyPatch applies the JSON patch of the admission.Response to a copy of the original object and returns the
+// mutated object. The patch is taken from the Patches of the response or, if they are empty, from the raw Patch.
+// An error is returned if the patch cannot be applied to the object.
+func ApplyPatch[T runtime.Object](original T, resp admission.Response) (T, error) {
+	var mutated T
+
+	raw, err := json.Marshal(original)
+	if err != nil {
+		return mutated, err
+	}
+
+	patches := resp.Patches
+	if len(patches) == 0 && len(resp.Patch) > 0 {
+		if resp.PatchType != nil && *resp.PatchType != admissionv1.PatchTypeJSONPatch {
+			return mutated, fmt.Errorf("unsupported patch type %q", *resp.PatchType)
+		}
+		if err := json.Unmarshal(resp.Patch, &patches); err != nil {
+			return mutated, err
+		}
+	}
+
+	if len(patches) > 0 {
+		if raw, err = webhook.ApplyJSONPatch(raw, patches); err != nil {
+			return mutated, err
+		}
+	}
+
+	mutated = reflect.New(reflect.TypeOf(original).Elem()).Interface().(T)
+	if err := json.Unmarshal(raw, mutated); err != nil {
+		return mutated, err
+	}
+
+	return mutated, nil
+}
@@ -0,0 +1,80 @@
+package webhooktest_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Patch", func() {
+	var (
+		pod *corev1.Pod
+	)
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "bar",
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "jin",
+			},
+		}
+	})
+	It("should apply patches of mutator", func() {
+		resp, err := webhooktest.Create(pod).Mutate(&webhook.MutateFunc{
+			Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+				obj.(*corev1.Pod).Spec.NodeName = ""
+				obj.(*corev1.Pod).Spec.Hostname = "yang"
+				return admission.Allowed("")
+			},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		mutated, err := webhooktest.ApplyPatch(pod, resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(mutated.Spec.NodeName).Should(BeEmpty())
+		Ω(mutated.Spec.Hostname).Should(Equal("yang"))
+		Ω(pod.Spec.NodeName).Should(Equal("jin"))
+	})
+	It("should apply raw patch", func() {
+		patchType := admissionv1.PatchTypeJSONPatch
+		resp := admission.Allowed("")
+		resp.Patch = []byte(`[{"op":"replace","path":"/metadata/name","value":"baz"}]`)
+		resp.PatchType = &patchType
+
+		mutated, err := webhooktest.ApplyPatch(pod, resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(mutated.Name).Should(Equal("baz"))
+	})
+	It("should return copy without patches", func() {
+		mutated, err := webhooktest.ApplyPatch(pod, admission.Allowed(""))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(mutated).Should(Equal(pod))
+		Ω(mutated).ShouldNot(BeIdenticalTo(pod))
+	})
+	It("should fail if patch cannot be applied", func() {
+		_, err := webhooktest.ApplyPatch(pod, admission.Patched("", jsonpatch.NewOperation("remove", "/spec/foo", nil)))
+		Ω(err).Should(HaveOccurred())
+	})
+	It("should fail for unsupported patch type", func() {
+		patchType := admissionv1.PatchType("Merge")
+		resp := admission.Allowed("")
+		resp.Patch = []byte(`{}`)
+		resp.PatchType = &patchType
+
+		_, err := webhooktest.ApplyPatch(pod, resp)
+		Ω(err).Should(HaveOccurred())
+	})
+})
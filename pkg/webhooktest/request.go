@@ -0,0 +1,170 @@
+// Package webhooktest provides request builders and Gomega matchers to test generic webhooks.
+package webhooktest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+)
+
+// RequestBuilder builds an admission.Request for testing a webhook.
+type RequestBuilder struct {
+	operation admissionv1.Operation
+	obj       runtime.Object
+	oldObj    runtime.Object
+	userInfo  authenticationv1.UserInfo
+	dryRun    bool
+	scheme    *runtime.Scheme
+	client    client.Client
+}
+
+// Create returns a new RequestBuilder for an AdmissionRequest with operation set to Create.
+func Create(obj runtime.Object) *RequestBuilder {
+	return &RequestBuilder{operation: admissionv1.Create, obj: obj, scheme: scheme.Scheme}
+}
+
+// Update returns a new RequestBuilder for an AdmissionRequest with operation set to Update.
+func Update(obj runtime.Object, oldObj runtime.Object) *RequestBuilder {
+	return &RequestBuilder{operation: admissionv1.Update, obj: obj, oldObj: oldObj, scheme: scheme.Scheme}
+}
+
+// Delete returns a new RequestBuilder for an AdmissionRequest with operation set to Delete.
+func Delete(oldObj runtime.Object) *RequestBuilder {
+	return &RequestBuilder{operation: admissionv1.Delete, oldObj: oldObj, scheme: scheme.Scheme}
+}
+
+// AsUser sets the user and the groups of the user which sent the request.
+func (b *RequestBuilder) AsUser(name string, groups ...string) *RequestBuilder {
+	b.userInfo = authenticationv1.UserInfo{Username: name, Groups: groups}
+	return b
+}
+
+// DryRun marks the request as dry run.
+func (b *RequestBuilder) DryRun() *RequestBuilder {
+	b.dryRun = true
+	return b
+}
+
+// WithScheme sets the runtime.Scheme used to determine the kind of the objects and to decode them in the handler,
+// default is the scheme of the Kubernetes client-go.
+func (b *RequestBuilder) WithScheme(scheme *runtime.Scheme) *RequestBuilder {
+	b.scheme = scheme
+	return b
+}
+
+// WithClient sets the client.Client which is injected into the webhook.
+func (b *RequestBuilder) WithClient(client client.Client) *RequestBuilder {
+	b.client = client
+	return b
+}
+
+// Build builds the admission.Request.
+func (b *RequestBuilder) Build() (admission.Request, error) {
+	req := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       uuid.NewUUID(),
+			Operation: b.operation,
+			UserInfo:  b.userInfo,
+			DryRun:    &b.dryRun,
+		},
+	}
+
+	obj := b.object()
+	if obj == nil {
+		return req, fmt.Errorf("request must contain an object")
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, b.scheme)
+	if err != nil {
+		return req, err
+	}
+	req.Kind = metav1.GroupVersionKind(gvk)
+	req.RequestKind = &req.Kind
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	req.Resource = metav1.GroupVersionResource(gvr)
+	req.RequestResource = &req.Resource
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return req, err
+	}
+	req.Name = accessor.GetName()
+	req.Namespace = accessor.GetNamespace()
+
+	if b.obj != nil {
+		if req.Object.Raw, err = json.Marshal(b.obj); err != nil {
+			return req, err
+		}
+	}
+	if b.oldObj != nil {
+		if req.OldObject.Raw, err = json.Marshal(b.oldObj); err != nil {
+			return req, err
+		}
+	}
+
+	return req, nil
+}
+
+// Validate runs the request through the generic handler of the validating webhook.
+func (b *RequestBuilder) Validate(validator webhook.Validator) (admission.Response, error) {
+	h, _, err := b.webhook().Build(validator)
+	if err != nil {
+		return admission.Response{}, err
+	}
+
+	return b.Handle(h)
+}
+
+// Mutate runs the request through the generic handler of the mutating webhook.
+func (b *RequestBuilder) Mutate(mutator webhook.Mutator) (admission.Response, error) {
+	_, h, err := b.webhook().Build(mutator)
+	if err != nil {
+		return admission.Response{}, err
+	}
+
+	return b.Handle(h)
+}
+
+// Handle runs the request through the given admission.Handler.
+func (b *RequestBuilder) Handle(h admission.Handler) (admission.Response, error) {
+	req, err := b.Build()
+	if err != nil {
+		return admission.Response{}, err
+	}
+
+	return h.Handle(context.TODO(), req), nil
+}
+
+// webhook returns a webhook.Builder for the type of the object in the request.
+func (b *RequestBuilder) webhook() *webhook.Builder {
+	var apiType runtime.Object
+	if obj := b.object(); obj != nil {
+		apiType = reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	}
+
+	return webhook.NewGenericWebhook(b.scheme).
+		WithClient(b.client).
+		For(apiType)
+}
+
+func (b *RequestBuilder) object() runtime.Object {
+	if b.obj != nil {
+		return b.obj
+	}
+
+	return b.oldObj
+}
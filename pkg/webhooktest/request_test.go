@@ -0,0 +1,107 @@
+package webhooktest_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhook"
+	"github.com/snorwin/k8s-generic-webhook/pkg/webhooktest"
+)
+
+var _ = Describe("Request", func() {
+	var (
+		pod *corev1.Pod
+	)
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "bar",
+			},
+		}
+	})
+	Context("Build", func() {
+		It("should build create request", func() {
+			req, err := webhooktest.Create(pod).AsUser("alice", "admins").DryRun().Build()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(req.UID).ShouldNot(BeEmpty())
+			Ω(req.Operation).Should(Equal(admissionv1.Create))
+			Ω(req.Kind).Should(Equal(metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}))
+			Ω(req.Resource).Should(Equal(metav1.GroupVersionResource{Version: "v1", Resource: "pods"}))
+			Ω(req.Name).Should(Equal("foo"))
+			Ω(req.Namespace).Should(Equal("bar"))
+			Ω(req.UserInfo.Username).Should(Equal("alice"))
+			Ω(req.UserInfo.Groups).Should(ConsistOf("admins"))
+			Ω(*req.DryRun).Should(BeTrue())
+			Ω(req.Object.Raw).ShouldNot(BeEmpty())
+			Ω(req.OldObject.Raw).Should(BeEmpty())
+		})
+		It("should build update request", func() {
+			req, err := webhooktest.Update(pod, pod.DeepCopy()).Build()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(req.Operation).Should(Equal(admissionv1.Update))
+			Ω(*req.DryRun).Should(BeFalse())
+			Ω(req.Object.Raw).ShouldNot(BeEmpty())
+			Ω(req.OldObject.Raw).ShouldNot(BeEmpty())
+		})
+		It("should build delete request", func() {
+			req, err := webhooktest.Delete(pod).Build()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(req.Operation).Should(Equal(admissionv1.Delete))
+			Ω(req.Name).Should(Equal("foo"))
+			Ω(req.Object.Raw).Should(BeEmpty())
+			Ω(req.OldObject.Raw).ShouldNot(BeEmpty())
+		})
+		It("should fail if object is not registered in scheme", func() {
+			_, err := webhooktest.Create(pod).WithScheme(runtime.NewScheme()).Build()
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should fail without object", func() {
+			_, err := webhooktest.Create(nil).Build()
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("Validate", func() {
+		It("should run validator", func() {
+			validator := &webhook.ValidateFuncs{
+				CreateFunc: func(_ context.Context, req admission.Request, obj runtime.Object) admission.Response {
+					if req.UserInfo.Username != "alice" {
+						return admission.Denied("only alice")
+					}
+					Ω(obj).Should(Equal(pod))
+					return admission.Allowed("")
+				},
+			}
+			Ω(webhooktest.Create(pod).AsUser("alice").Validate(validator)).Should(webhooktest.BeAllowed())
+			Ω(webhooktest.Create(pod).AsUser("bob").Validate(validator)).Should(webhooktest.BeDeniedWithReason("only alice"))
+		})
+		It("should inject client and decoder", func() {
+			validator := &webhook.ValidatingWebhook{}
+			Ω(webhooktest.Delete(pod).Validate(validator)).Should(webhooktest.BeAllowed())
+			Ω(validator.Decoder).ShouldNot(BeNil())
+		})
+	})
+	Context("Mutate", func() {
+		It("should run mutator", func() {
+			mutator := &webhook.MutateFunc{
+				Func: func(_ context.Context, _ admission.Request, obj runtime.Object) admission.Response {
+					obj.(*corev1.Pod).Name = "baz"
+					return admission.Allowed("").WithWarnings("renamed")
+				},
+			}
+			resp, err := webhooktest.Create(pod).Mutate(mutator)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp).Should(webhooktest.BeAllowed())
+			Ω(resp).Should(webhooktest.HaveWarning("renamed"))
+			Ω(resp).Should(webhooktest.HavePatch("replace", "/metadata/name", "baz"))
+		})
+	})
+})
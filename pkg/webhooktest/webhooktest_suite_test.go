@@ -0,0 +1,13 @@
+package webhooktest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhookTest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WebhookTest Test Suite")
+}